@@ -0,0 +1,385 @@
+package birthday_greetings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API is a JSON HTTP API to manage friends and trigger sends. Friends are
+// addressed by ID. Updates and deletions require an If-Match
+// header carrying the ETag returned when the friend was read, so concurrent
+// edits cannot silently overwrite each other.
+type API struct {
+	mu        sync.Mutex
+	store     FriendsStore
+	scheduler *Scheduler
+	mux       *http.ServeMux
+}
+
+type greetingResponse struct {
+	Recipient string         `json:"recipient"`
+	Title     string         `json:"title"`
+	Message   string         `json:"message"`
+	Status    DeliveryStatus `json:"status,omitempty"`
+}
+
+type sendRequest struct {
+	Date   string `json:"date"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type sendResponse struct {
+	DryRun    bool               `json:"dry_run"`
+	Greetings []greetingResponse `json:"greetings"`
+	Error     string             `json:"error,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func NewAPI(store FriendsStore, scheduler *Scheduler) *API {
+	api := &API{store: store, scheduler: scheduler, mux: http.NewServeMux()}
+
+	api.mux.HandleFunc("GET /friends", api.listFriends)
+	api.mux.HandleFunc("POST /friends", api.createFriend)
+	api.mux.HandleFunc("GET /friends/{id}", api.getFriend)
+	api.mux.HandleFunc("PUT /friends/{id}", api.updateFriend)
+	api.mux.HandleFunc("DELETE /friends/{id}", api.deleteFriend)
+	api.mux.HandleFunc("GET /friends/{id}/greeting", api.previewGreeting)
+	api.mux.HandleFunc("POST /sends", api.triggerSend)
+
+	return api
+}
+
+func (api *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mux.ServeHTTP(w, r)
+}
+
+// listFriends lists every friend, or those whose name or email contains the
+// q query parameter and whose tags match the tags query parameter.
+func (api *API) listFriends(w http.ResponseWriter, r *http.Request) {
+	friends, err := api.store.GetFriends()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tags, err := parseRequestTags(r.URL.Query().Get("tags"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	friends = FilterFriends(friends, tags)
+
+	query := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	matches := []Friend{}
+	for _, friend := range friends {
+		haystack := strings.ToLower(strings.Join([]string{friend.FirstName, friend.LastName, friend.Email}, " "))
+		if strings.Contains(haystack, query) {
+			matches = append(matches, friend)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, matches)
+}
+
+func (api *API) getFriend(w http.ResponseWriter, r *http.Request) {
+	friends, err := api.store.GetFriends()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	index := findFriend(friends, r.PathValue("id"))
+	if index < 0 {
+		writeError(w, http.StatusNotFound, errors.New("friend not found"))
+		return
+	}
+
+	w.Header().Set("ETag", friendETag(friends[index]))
+	writeJSON(w, http.StatusOK, friends[index])
+}
+
+func (api *API) createFriend(w http.ResponseWriter, r *http.Request) {
+	friend, err := decodeFriend(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	friends, err := api.store.GetFriends()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	friend.ID = friend.identity()
+	if findFriend(friends, friend.ID) >= 0 {
+		writeError(w, http.StatusConflict, errors.New("friend already exists"))
+		return
+	}
+
+	if err := api.store.SaveFriends(append(friends, friend)); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("ETag", friendETag(friend))
+	w.Header().Set("Location", "/friends/"+friend.ID)
+	writeJSON(w, http.StatusCreated, friend)
+}
+
+func (api *API) updateFriend(w http.ResponseWriter, r *http.Request) {
+	friend, err := decodeFriend(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	friends, index, ok := api.matchingFriend(w, r)
+	if !ok {
+		return
+	}
+
+	if friend.ID != "" && friend.ID != friends[index].ID {
+		writeError(w, http.StatusBadRequest, errors.New("friend id cannot be changed"))
+		return
+	}
+
+	friend.ID = friends[index].ID
+	friends[index] = friend
+	if err := api.store.SaveFriends(friends); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("ETag", friendETag(friend))
+	writeJSON(w, http.StatusOK, friend)
+}
+
+func (api *API) deleteFriend(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	friends, index, ok := api.matchingFriend(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.store.SaveFriends(append(friends[:index], friends[index+1:]...)); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// matchingFriend loads the friend addressed by the request and checks its
+// If-Match header, writing the error response when it does not match.
+func (api *API) matchingFriend(w http.ResponseWriter, r *http.Request) ([]Friend, int, bool) {
+	friends, err := api.store.GetFriends()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, 0, false
+	}
+
+	index := findFriend(friends, r.PathValue("id"))
+	if index < 0 {
+		writeError(w, http.StatusNotFound, errors.New("friend not found"))
+		return nil, 0, false
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeError(w, http.StatusPreconditionRequired, errors.New("If-Match header is required"))
+		return nil, 0, false
+	}
+
+	if ifMatch != friendETag(friends[index]) {
+		writeError(w, http.StatusPreconditionFailed, errors.New("friend was modified"))
+		return nil, 0, false
+	}
+
+	return friends, index, true
+}
+
+// previewGreeting renders the greeting a friend would receive on the date
+// query parameter, which defaults to today.
+func (api *API) previewGreeting(w http.ResponseWriter, r *http.Request) {
+	friends, err := api.store.GetFriends()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	index := findFriend(friends, r.PathValue("id"))
+	if index < 0 {
+		writeError(w, http.StatusNotFound, errors.New("friend not found"))
+		return
+	}
+
+	date, err := parseRequestDate(r.URL.Query().Get("date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	greetings, err := api.scheduler.Preview(friends[index], date)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toGreetingResponse(greetings))
+}
+
+// triggerSend sends the greetings due on the requested date, reporting
+// whether each one was sent, failed or held for the send window. A dry run
+// only reports the greetings that would be sent. Manual sends leave catch-up,
+// retries, held greetings, the digest and the run marker to scheduled runs.
+func (api *API) triggerSend(w http.ResponseWriter, r *http.Request) {
+	var request sendRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid JSON body"))
+		return
+	}
+
+	date, err := parseRequestDate(request.Date)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := sendResponse{DryRun: request.DryRun, Greetings: []greetingResponse{}}
+
+	if request.DryRun {
+		var greetings []BirthdayGreetings
+		greetings, err = api.scheduler.planOn(date)
+		for _, greeting := range greetings {
+			planned := toGreetingResponse(greeting)
+			planned.Status = Planned
+			response.Greetings = append(response.Greetings, planned)
+		}
+	} else {
+		var delivered []scheduledGreeting
+		delivered, err = api.scheduler.sendOn(date, time.Now())
+		for _, scheduled := range delivered {
+			greeting := toGreetingResponse(scheduled.greetings)
+			greeting.Status = scheduled.status
+			response.Greetings = append(response.Greetings, greeting)
+		}
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusInternalServerError
+	}
+
+	writeJSON(w, status, response)
+}
+
+func decodeFriend(r *http.Request) (Friend, error) {
+	var friend Friend
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&friend); err != nil {
+		return Friend{}, errors.New("invalid JSON body")
+	}
+
+	friend.Source = ""
+	if err := friend.validate(); err != nil {
+		return Friend{}, err
+	}
+
+	if _, err := parseBirthDate(friend.BirthDate); err != nil {
+		return Friend{}, err
+	}
+
+	// The address ends up in message headers, so only a bare address is
+	// accepted.
+	if address, err := mail.ParseAddress(friend.Email); err != nil || address.Name != "" || address.Address != friend.Email {
+		return Friend{}, errors.New("invalid email")
+	}
+
+	if _, err := friend.location(); err != nil {
+		return Friend{}, errors.New("invalid time zone")
+	}
+
+	for _, event := range friend.Events {
+		if _, err := event.OccursOn(time.Now()); err != nil {
+			return Friend{}, err
+		}
+	}
+
+	return friend, nil
+}
+
+// parseRequestDate parses a "YYYY/MM/DD" date, defaulting to today in UTC.
+func parseRequestDate(value string) (time.Time, error) {
+	if value == "" {
+		return startOfDay(time.Now().UTC()), nil
+	}
+
+	date, err := time.Parse(birthDateLayout, value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid date, expected YYYY/MM/DD")
+	}
+
+	return date, nil
+}
+
+// parseRequestTags parses the tags query parameter, matching every friend
+// when it is missing.
+func parseRequestTags(value string) (TagExpression, error) {
+	if strings.TrimSpace(value) == "" {
+		return TagExpression{}, nil
+	}
+
+	return ParseTagExpression(value)
+}
+
+func findFriend(friends []Friend, id string) int {
+	for i, friend := range friends {
+		if friend.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func friendETag(friend Friend) string {
+	friend.Source = ""
+	data, _ := json.Marshal(friend)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func toGreetingResponse(greetings BirthdayGreetings) greetingResponse {
+	return greetingResponse{Recipient: greetings.recipient, Title: greetings.title, Message: greetings.message}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,265 @@
+package birthday_greetings
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAPI(t *testing.T) (*API, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content, err := os.ReadFile("birthdays.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repository := TextFileFriendsRepository{path: path}
+
+	return NewAPI(repository, NewScheduler(repository, SchedulerConfig{})), path
+}
+
+func serve(api *API, method string, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	api.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAPIListsAndSearchesFriends(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	response := serve(api, http.MethodGet, "/friends?q=mary", "", nil)
+
+	var friends []Friend
+	if err := json.NewDecoder(response.Body).Decode(&friends); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Code != http.StatusOK || len(friends) != 1 || friends[0].FirstName != "Mary" {
+		t.Errorf("Expected to find Mary but got %d %v", response.Code, friends)
+	}
+}
+
+func TestAPICreatesFriend(t *testing.T) {
+	api, path := newTestAPI(t)
+	body := `{"last_name":"Smith","first_name":"Jane","birth_date":"1990/05/15","email":"jane.smith@example.com"}`
+
+	response := serve(api, http.MethodPost, "/friends", body, nil)
+
+	if response.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 but got %d: %s", response.Code, response.Body)
+	}
+
+	friends, _ := TextFileFriendsRepository{path: path}.GetFriends()
+	if len(friends) != 3 {
+		t.Errorf("Expected 3 friends to be saved but got %d", len(friends))
+	}
+}
+
+func TestAPIRejectsInvalidFriend(t *testing.T) {
+	api, _ := newTestAPI(t)
+	body := `{"last_name":"Smith","first_name":"Jane","birth_date":"15/05/1990","email":"jane.smith@example.com"}`
+
+	response := serve(api, http.MethodPost, "/friends", body, nil)
+
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 but got %d", response.Code)
+	}
+}
+
+func TestAPIRejectsInvalidEmail(t *testing.T) {
+	api, path := newTestAPI(t)
+
+	for _, email := range []string{`jane.smith@example.com\r\nBcc: victim@example.com`, "Jane <jane.smith@example.com>", "jane.smith"} {
+		body := `{"last_name":"Smith","first_name":"Jane","birth_date":"1990/05/15","email":"` + email + `"}`
+
+		if response := serve(api, http.MethodPost, "/friends", body, nil); response.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q but got %d", email, response.Code)
+		}
+	}
+
+	if friends, _ := (TextFileFriendsRepository{path: path}).GetFriends(); len(friends) != 2 {
+		t.Errorf("Expected no friend to be saved but got %d friends", len(friends))
+	}
+}
+
+func TestAPIRejectsDuplicateFriend(t *testing.T) {
+	api, _ := newTestAPI(t)
+	body := `{"id":"c4f77c3c468f1411","last_name":"Doe","first_name":"Johnny","birth_date":"1982/10/08","email":"johnny@foobar.com"}`
+
+	response := serve(api, http.MethodPost, "/friends", body, nil)
+
+	if response.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 but got %d", response.Code)
+	}
+}
+
+func TestAPIUpdatesFriendWithMatchingETag(t *testing.T) {
+	api, _ := newTestAPI(t)
+	etag := serve(api, http.MethodGet, "/friends/c4f77c3c468f1411", "", nil).Header().Get("ETag")
+	body := `{"last_name":"Doe","first_name":"Johnny","birth_date":"1982/10/08","email":"john.doe@foobar.com"}`
+
+	response := serve(api, http.MethodPut, "/friends/c4f77c3c468f1411", body, map[string]string{"If-Match": etag})
+
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected status 200 but got %d: %s", response.Code, response.Body)
+	}
+
+	if got := serve(api, http.MethodGet, "/friends/c4f77c3c468f1411", "", nil); got.Code != http.StatusOK {
+		t.Errorf("Expected the friend to keep its id after an update but got %d", got.Code)
+	}
+
+	stale := serve(api, http.MethodPut, "/friends/c4f77c3c468f1411", body, map[string]string{"If-Match": etag})
+	if stale.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 for a stale ETag but got %d", stale.Code)
+	}
+}
+
+func TestAPIRequiresIfMatchToDelete(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	response := serve(api, http.MethodDelete, "/friends/c4f77c3c468f1411", "", nil)
+
+	if response.Code != http.StatusPreconditionRequired {
+		t.Errorf("Expected status 428 but got %d", response.Code)
+	}
+}
+
+func TestAPIDeletesFriend(t *testing.T) {
+	api, _ := newTestAPI(t)
+	etag := serve(api, http.MethodGet, "/friends/c4f77c3c468f1411", "", nil).Header().Get("ETag")
+
+	response := serve(api, http.MethodDelete, "/friends/c4f77c3c468f1411", "", map[string]string{"If-Match": etag})
+
+	if response.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 but got %d", response.Code)
+	}
+
+	if missing := serve(api, http.MethodGet, "/friends/c4f77c3c468f1411", "", nil); missing.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after deletion but got %d", missing.Code)
+	}
+}
+
+func TestAPIPreviewsGreeting(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	response := serve(api, http.MethodGet, "/friends/1ce90aa149d9fed9/greeting?date=2024/09/11", "", nil)
+
+	var greeting greetingResponse
+	json.NewDecoder(response.Body).Decode(&greeting)
+	want := "Happy birthday, dear Mary Ann! Congratulations on turning 49!"
+
+	if response.Code != http.StatusOK || greeting.Message != want {
+		t.Errorf("Expected '%s' but got %d '%s'", want, response.Code, greeting.Message)
+	}
+}
+
+func TestAPITriggersDryRunSend(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	response := serve(api, http.MethodPost, "/sends", `{"date":"2024/10/08","dry_run":true}`, nil)
+
+	var result sendResponse
+	json.NewDecoder(response.Body).Decode(&result)
+
+	if response.Code != http.StatusOK || !result.DryRun || len(result.Greetings) != 1 || result.Greetings[0].Recipient != "john.doe@foobar.com" {
+		t.Errorf("Expected a dry run greeting for John but got %d %v", response.Code, result)
+	}
+}
+
+func TestAPIRejectsSendWithInvalidDate(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	response := serve(api, http.MethodPost, "/sends", `{"date":"tomorrow"}`, nil)
+
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 but got %d", response.Code)
+	}
+}
+
+func TestAPIManualSendReportsStatusesAndKeepsRunMarker(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/01", Email: "john.doe@foobar.com"},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/01", Email: "mary.ann@foobar.com", TimeZone: "Asia/Tokyo"},
+	}}
+	marker := NewFileRunMarker(filepath.Join(t.TempDir(), "last_run"))
+	lastRun := time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)
+	marker.MarkRun(lastRun)
+	scheduler := NewScheduler(repository, SchedulerConfig{Marker: marker, Window: SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour}})
+	api := NewAPI(nil, scheduler)
+
+	response := serve(api, http.MethodPost, "/sends", `{"date":"2024/10/01"}`, nil)
+
+	var result sendResponse
+	json.NewDecoder(response.Body).Decode(&result)
+
+	statuses := map[string]DeliveryStatus{}
+	for _, greeting := range result.Greetings {
+		statuses[greeting.Recipient] = greeting.Status
+	}
+
+	if statuses["john.doe@foobar.com"] != Sent || statuses["mary.ann@foobar.com"] != Held {
+		t.Errorf("Expected John's greeting sent and Mary's held but got %d %v", response.Code, result)
+	}
+
+	if marked, _, _ := marker.LastRun(); !marked.Equal(lastRun) {
+		t.Errorf("Expected the run marker to stay at %v but got %v", lastRun, marked)
+	}
+}
+
+func TestAPIManualSendForFutureDateLeavesCatchUpToScheduledRuns(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/10", Email: "mary.ann@foobar.com"},
+	}}
+	marker := NewFileRunMarker(filepath.Join(t.TempDir(), "last_run"))
+	marker.MarkRun(time.Date(2024, 10, 5, 9, 0, 0, 0, time.UTC))
+	scheduler := NewScheduler(repository, SchedulerConfig{Marker: marker, MaxCatchUpDays: 7})
+	api := NewAPI(nil, scheduler)
+	started := time.Now()
+
+	response := serve(api, http.MethodPost, "/sends", `{"date":"2024/10/10"}`, nil)
+
+	var result sendResponse
+	json.NewDecoder(response.Body).Decode(&result)
+
+	if response.Code != http.StatusOK || len(result.Greetings) != 1 || result.Greetings[0].Recipient != "mary.ann@foobar.com" {
+		t.Errorf("Expected only Mary's greeting but got %d %v", response.Code, result)
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{})
+	if len(receipts) != 1 || receipts[0].SentAt.Before(started) {
+		t.Errorf("Expected one receipt stamped with the real clock but got %v", receipts)
+	}
+
+	greetings, _ := scheduler.Plan(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+	if len(greetings) != 1 || greetings[0].recipient != "john.doe@foobar.com" || strings.Contains(greetings[0].message, "Sorry") {
+		t.Errorf("Expected John's greeting on his birthday but got %v", greetings)
+	}
+}
+
+func TestAPIPreviewsGroupTemplates(t *testing.T) {
+	api, _ := newTestAPI(t)
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	api.scheduler.config.Groups = []FriendGroup{{Name: "everyone", Options: &MessageOptions{EventTemplates: map[EventType]EventTemplate{Birthday: {Template: "Cheers, {{.FirstName}}!"}}}}}
+
+	response := serve(api, http.MethodGet, "/friends/"+friend.derivedID()+"/greeting?date=2024/10/08", "", nil)
+
+	var greeting greetingResponse
+	json.NewDecoder(response.Body).Decode(&greeting)
+
+	if response.Code != http.StatusOK || greeting.Message != "Cheers, John!" {
+		t.Errorf("Expected the group template but got %d '%s'", response.Code, greeting.Message)
+	}
+}
@@ -0,0 +1,338 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MboxSender appends every greeting to an mbox file, escaping body lines
+// that start with "From " as in the mboxrd format.
+type MboxSender struct {
+	mu   sync.Mutex
+	path string
+	from string
+	now  func() time.Time
+}
+
+// MaildirSender delivers every greeting into the new directory of a Maildir,
+// creating the tmp, new and cur directories when missing.
+type MaildirSender struct {
+	dir  string
+	from string
+	now  func() time.Time
+}
+
+// TeeSender sends greetings through a primary sender and, when that
+// succeeds, archives them through every archive sender. The receipt is the
+// primary sender's. Mbox and Maildir archives store the exact message the
+// primary sender handed to its transport, when it reports one.
+type TeeSender struct {
+	primary  Sender
+	archives []Sender
+}
+
+// ArchivedMessage is a greeting found in an archive. Content is only filled
+// in when the message is exported.
+type ArchivedMessage struct {
+	Archive   string `json:"archive"`
+	MessageID string `json:"message_id"`
+	Content   string `json:"content,omitempty"`
+}
+
+var mboxFromLine = regexp.MustCompile(`(?m)^(>*From )`)
+
+var maildirDeliveries atomic.Int64
+
+func NewMboxSender(path string, from string) *MboxSender {
+	return &MboxSender{path: path, from: from, now: time.Now}
+}
+
+func (sender *MboxSender) Send(greetings BirthdayGreetings) (Receipt, error) {
+	message, err := formatMessage(greetings, sender.from, sender.now())
+	if err != nil {
+		return Receipt{Channel: "mbox"}, err
+	}
+
+	return sender.archive(message)
+}
+
+// archive appends message, a CRLF separated RFC 5322 message, to the mbox.
+func (sender *MboxSender) archive(message []byte) (Receipt, error) {
+	receipt := Receipt{Channel: "mbox", message: message}
+	now := sender.now()
+
+	message = bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n"))
+	message = mboxFromLine.ReplaceAll(message, []byte(">$1"))
+
+	var entry bytes.Buffer
+	fmt.Fprintf(&entry, "From %s %s\n", sender.from, now.UTC().Format(time.ANSIC))
+	entry.Write(message)
+	entry.WriteString("\n")
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	file, err := os.OpenFile(sender.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return receipt, err
+	}
+
+	if _, err := file.Write(entry.Bytes()); err != nil {
+		file.Close()
+		return receipt, err
+	}
+
+	if err := file.Close(); err != nil {
+		return receipt, err
+	}
+
+	receipt.ProviderResponse = "appended to " + sender.path
+	return receipt, nil
+}
+
+// Find returns the archived messages in selection.
+func (sender *MboxSender) Find(selection ArchiveSelection) ([]ArchivedMessage, error) {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	found, _, err := sender.scan(selection)
+	return found, err
+}
+
+// Erase rewrites the mbox without the messages in selection and returns the
+// removed messages.
+func (sender *MboxSender) Erase(selection ArchiveSelection) ([]ArchivedMessage, error) {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	found, kept, err := sender.scan(selection)
+	if err != nil || len(found) == 0 {
+		return found, err
+	}
+
+	return found, writeFileAtomically(sender.path, []byte(strings.Join(kept, "")))
+}
+
+// scan splits the mbox into entries, separating those in selection from the
+// ones to keep.
+func (sender *MboxSender) scan(selection ArchiveSelection) ([]ArchivedMessage, []string, error) {
+	data, err := os.ReadFile(sender.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := []string{}
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if strings.HasPrefix(line, "From ") || len(entries) == 0 {
+			entries = append(entries, "")
+		}
+		entries[len(entries)-1] += line
+	}
+
+	found, kept := []ArchivedMessage{}, []string{}
+	for _, entry := range entries {
+		_, message, _ := strings.Cut(entry, "\n")
+		if messageID, matches := selection.matches(message); matches {
+			found = append(found, ArchivedMessage{Archive: sender.path, MessageID: messageID, Content: message})
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+
+	return found, kept, nil
+}
+
+func NewMaildirSender(dir string, from string) *MaildirSender {
+	return &MaildirSender{dir: dir, from: from, now: time.Now}
+}
+
+// Send writes the message to tmp and then renames it into new, so mail
+// readers never see a partial message.
+func (sender *MaildirSender) Send(greetings BirthdayGreetings) (Receipt, error) {
+	message, err := formatMessage(greetings, sender.from, sender.now())
+	if err != nil {
+		return Receipt{Channel: "maildir"}, err
+	}
+
+	return sender.archive(message)
+}
+
+// archive delivers message, a CRLF separated RFC 5322 message, into new.
+func (sender *MaildirSender) archive(message []byte) (Receipt, error) {
+	receipt := Receipt{Channel: "maildir", message: message}
+	now := sender.now()
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(sender.dir, sub), 0o700); err != nil {
+			return receipt, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), maildirDeliveries.Add(1), hostname)
+	tmp := filepath.Join(sender.dir, "tmp", name)
+
+	if err := os.WriteFile(tmp, message, 0o600); err != nil {
+		return receipt, err
+	}
+
+	if err := os.Rename(tmp, filepath.Join(sender.dir, "new", name)); err != nil {
+		os.Remove(tmp)
+		return receipt, err
+	}
+
+	receipt.ProviderResponse = "delivered to " + filepath.Join(sender.dir, "new", name)
+	return receipt, nil
+}
+
+// Find returns the messages in new and cur that are in selection.
+func (sender *MaildirSender) Find(selection ArchiveSelection) ([]ArchivedMessage, error) {
+	return sender.scan(selection, false)
+}
+
+// Erase deletes the messages in selection and returns them.
+func (sender *MaildirSender) Erase(selection ArchiveSelection) ([]ArchivedMessage, error) {
+	return sender.scan(selection, true)
+}
+
+func (sender *MaildirSender) scan(selection ArchiveSelection, erase bool) ([]ArchivedMessage, error) {
+	found := []ArchivedMessage{}
+
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(sender.dir, sub))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return found, err
+		}
+
+		for _, entry := range entries {
+			path := filepath.Join(sender.dir, sub, entry.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return found, err
+			}
+
+			messageID, matches := selection.matches(string(data))
+			if !matches {
+				continue
+			}
+
+			if erase {
+				if err := os.Remove(path); err != nil {
+					return found, err
+				}
+			}
+
+			found = append(found, ArchivedMessage{Archive: path, MessageID: messageID, Content: string(data)})
+		}
+	}
+
+	return found, nil
+}
+
+// ArchiveSelection picks the archived messages sent to any of Recipients or
+// carrying any of MessageIDs.
+type ArchiveSelection struct {
+	Recipients []string
+	MessageIDs []string
+}
+
+// matches reports whether message is in the selection, along with its
+// message ID.
+func (selection ArchiveSelection) matches(message string) (string, bool) {
+	parsed, err := mail.ReadMessage(strings.NewReader(message))
+	if err != nil {
+		return "", false
+	}
+
+	to := normalizeEmail(parsed.Header.Get("To"))
+	messageID := strings.Trim(parsed.Header.Get("Message-ID"), "<>")
+
+	for _, recipient := range selection.Recipients {
+		if recipient = normalizeEmail(recipient); recipient != "" && recipient == to {
+			return messageID, true
+		}
+	}
+
+	for _, id := range selection.MessageIDs {
+		if id != "" && id == messageID {
+			return messageID, true
+		}
+	}
+
+	return messageID, false
+}
+
+func NewTeeSender(primary Sender, archives ...Sender) TeeSender {
+	return TeeSender{primary: primary, archives: archives}
+}
+
+// Send returns an ArchiveError when archiving fails after the primary
+// sender succeeded, so that the greeting still counts as sent: retrying it
+// would deliver it twice. The archive errors are also noted in the provider
+// response.
+func (sender TeeSender) Send(greetings BirthdayGreetings) (Receipt, error) {
+	receipt, err := sender.primary.Send(greetings)
+	if err != nil {
+		return receipt, err
+	}
+
+	errs := []error{}
+	for _, archive := range sender.archives {
+		if store, ok := archive.(messageArchive); ok && receipt.message != nil {
+			_, err = store.archive(receipt.message)
+		} else {
+			_, err = archive.Send(greetings)
+		}
+
+		if err != nil {
+			receipt.ProviderResponse += "; archiving failed: " + err.Error()
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return receipt, &ArchiveError{Err: err}
+	}
+
+	return receipt, nil
+}
+
+// messageArchive stores a message exactly as another sender formatted it.
+type messageArchive interface {
+	archive(message []byte) (Receipt, error)
+}
+
+// ArchiveError reports archive copies that could not be written after the
+// greeting was delivered.
+type ArchiveError struct {
+	Err error
+}
+
+func (err *ArchiveError) Error() string {
+	return "archiving failed: " + err.Err.Error()
+}
+
+func (err *ArchiveError) Unwrap() error {
+	return err.Err
+}
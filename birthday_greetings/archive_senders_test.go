@@ -0,0 +1,149 @@
+package birthday_greetings
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func archiveTestGreetings(message string) BirthdayGreetings {
+	return BirthdayGreetings{title: "Happy Birthday", message: message, recipient: "jane.smith@example.com"}
+}
+
+func TestMboxSenderAppendsMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greetings.mbox")
+	sender := NewMboxSender(path, "greetings@example.com")
+	sender.now = func() time.Time { return time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC) }
+
+	archiveTestGreetings("Happy birthday, dear Jane Doe!").SendWith(sender)
+	receipt, err := archiveTestGreetings("Happy birthday!\nFrom all of us").SendWith(sender)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if receipt.Channel != "mbox" {
+		t.Errorf("Expected an mbox receipt but got %v", receipt)
+	}
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+
+	if count := strings.Count(content, "From greetings@example.com Mon Oct 19 09:00:00 2026\n"); count != 2 {
+		t.Errorf("Expected 2 mbox entries but got %v in %v", count, content)
+	}
+
+	if !strings.Contains(content, "\n>From all of us\n") || strings.Contains(content, "\r\n") {
+		t.Errorf("Expected escaped LF separated messages but got %v", content)
+	}
+
+	if !strings.Contains(content, "Message-ID: <"+receipt.MessageID+">\n") {
+		t.Errorf("Expected the receipt message ID in the archive but got %v", content)
+	}
+}
+
+func TestMaildirSenderDeliversIntoNew(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Maildir")
+	sender := NewMaildirSender(dir, "greetings@example.com")
+
+	for i := 0; i < 2; i++ {
+		if _, err := archiveTestGreetings("Happy birthday, dear Jane Doe!").SendWith(sender); err != nil {
+			t.Fatalf("Expected no error but got '%v'", err.Error())
+		}
+	}
+
+	delivered, _ := os.ReadDir(filepath.Join(dir, "new"))
+	if len(delivered) != 2 {
+		t.Fatalf("Expected 2 messages in new but got %v", len(delivered))
+	}
+
+	if pending, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(pending) != 0 {
+		t.Errorf("Expected tmp to be empty but got %v", len(pending))
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "new", delivered[0].Name()))
+	if !strings.Contains(string(data), "\r\n\r\nHappy birthday, dear Jane Doe!\r\n") {
+		t.Errorf("Expected the greeting in the delivered message but got %v", string(data))
+	}
+}
+
+func TestTeeSenderArchivesSuccessfulSends(t *testing.T) {
+	dir := t.TempDir()
+	mbox := filepath.Join(dir, "greetings.mbox")
+	sender := NewTeeSender(NoopSender{}, NewMboxSender(mbox, "greetings@example.com"), NewMaildirSender(filepath.Join(dir, "Maildir"), "greetings@example.com"))
+
+	receipt, err := archiveTestGreetings("Happy birthday, dear Jane Doe!").SendWith(sender)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if receipt.Channel != "noop" || receipt.ProviderResponse != "accepted" {
+		t.Errorf("Expected the primary receipt but got %v", receipt)
+	}
+
+	if _, err := os.Stat(mbox); err != nil {
+		t.Errorf("Expected the greeting to be archived in the mbox but got '%v'", err.Error())
+	}
+
+	if delivered, _ := os.ReadDir(filepath.Join(dir, "Maildir", "new")); len(delivered) != 1 {
+		t.Errorf("Expected the greeting to be archived in the Maildir but got %v", len(delivered))
+	}
+}
+
+func TestTeeSenderSkipsArchivesWhenSendFails(t *testing.T) {
+	mbox := filepath.Join(t.TempDir(), "greetings.mbox")
+	sender := NewTeeSender(failingSender{}, NewMboxSender(mbox, "greetings@example.com"))
+
+	if _, err := archiveTestGreetings("Happy birthday, dear Jane Doe!").SendWith(sender); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	if _, err := os.Stat(mbox); !os.IsNotExist(err) {
+		t.Errorf("Expected no archive for a failed send but got '%v'", err)
+	}
+}
+
+func TestTeeSenderKeepsSendWhenArchiveFails(t *testing.T) {
+	sender := NewTeeSender(NoopSender{}, NewMboxSender(filepath.Join(t.TempDir(), "missing", "greetings.mbox"), "greetings@example.com"))
+
+	receipt, err := archiveTestGreetings("Happy birthday, dear Jane Doe!").SendWith(sender)
+
+	var archiveErr *ArchiveError
+	if !errors.As(err, &archiveErr) {
+		t.Errorf("Expected an archive error but got '%v'", err)
+	}
+
+	if receipt.Status != Sent {
+		t.Errorf("Expected the greeting to stay sent but got %v", receipt)
+	}
+
+	if !strings.Contains(receipt.ProviderResponse, "archiving failed") {
+		t.Errorf("Expected the archive failure in the provider response but got %v", receipt.ProviderResponse)
+	}
+}
+
+func TestTeeSenderArchivesTheMessageSent(t *testing.T) {
+	var captured capturedMail
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := NewDKIMSigner(private, DKIMOptions{Domain: "example.com", Selector: "greetings"})
+	dir := filepath.Join(t.TempDir(), "Maildir")
+	sender := NewTeeSender(newTestSMTPSender(&captured, nil).WithDKIM(signer), NewMaildirSender(dir, "archive@example.com"))
+
+	if _, err := archiveTestGreetings("Happy birthday, dear Jane Doe!").SendWith(sender); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	delivered, _ := os.ReadDir(filepath.Join(dir, "new"))
+	if len(delivered) != 1 {
+		t.Fatalf("Expected 1 message in new but got %v", len(delivered))
+	}
+
+	archived, _ := os.ReadFile(filepath.Join(dir, "new", delivered[0].Name()))
+	if string(archived) != string(captured.message) {
+		t.Errorf("Expected %q but got %q", captured.message, archived)
+	}
+}
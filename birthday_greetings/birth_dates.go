@@ -0,0 +1,69 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"time"
+)
+
+const birthDateLayout = "2006/01/02"
+
+const yearlessBirthDateLayout = "01/02"
+
+type birthDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// parseBirthDate accepts either a full "YYYY/MM/DD" date or a "MM/DD" date
+// for friends whose birth year is unknown, in which case year is zero.
+func parseBirthDate(value string) (birthDate, error) {
+	if date, err := time.Parse(birthDateLayout, value); err == nil {
+		return birthDate{year: date.Year(), month: date.Month(), day: date.Day()}, nil
+	}
+
+	date, err := time.Parse(yearlessBirthDateLayout, value)
+	if err != nil {
+		return birthDate{}, errors.New("invalid birth date")
+	}
+
+	return birthDate{month: date.Month(), day: date.Day()}, nil
+}
+
+func (date birthDate) hasYear() bool {
+	return date.year != 0
+}
+
+// ageOn returns the age turned in the year of on, or zero when the birth year
+// is unknown or after the year of on.
+func (date birthDate) ageOn(on time.Time) int {
+	if !date.hasYear() || on.Year() < date.year {
+		return 0
+	}
+
+	return on.Year() - date.year
+}
+
+func (friend Friend) IsBirthdayOn(date time.Time) (bool, error) {
+	birth, err := parseBirthDate(friend.BirthDate)
+	if err != nil {
+		return false, err
+	}
+
+	return sameAnniversary(birth.month, birth.day, date), nil
+}
+
+// sameAnniversary reports whether date is the yearly anniversary of the given
+// month and day. February 29 anniversaries fall on February 28 in non-leap
+// years.
+func sameAnniversary(month time.Month, day int, date time.Time) bool {
+	if month == time.February && day == 29 && !isLeapYear(date.Year()) {
+		day = 28
+	}
+
+	return date.Month() == month && date.Day() == day
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
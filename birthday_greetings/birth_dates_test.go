@@ -0,0 +1,42 @@
+package birthday_greetings
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsBirthdayOnLeapDayInNonLeapYear(t *testing.T) {
+	friend := Friend{FirstName: "Leap", LastName: "Day", BirthDate: "2000/02/29", Email: "leap@example.com"}
+
+	isBirthday, err := friend.IsBirthdayOn(time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !isBirthday {
+		t.Errorf("Expected February 28 to be the birthday in a non-leap year")
+	}
+}
+
+func TestIsBirthdayOnWithInvalidBirthDate(t *testing.T) {
+	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "15/05/1990", Email: "jane.smith@example.com"}
+
+	_, err := friend.IsBirthdayOn(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC))
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestIsBirthdayOnWithoutBirthYear(t *testing.T) {
+	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "05/15", Email: "jane.smith@example.com"}
+
+	isBirthday, err := friend.IsBirthdayOn(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !isBirthday {
+		t.Errorf("Expected May 15 to be the birthday")
+	}
+}
@@ -0,0 +1,127 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultBirthdayTitle = "Happy Birthday"
+
+const defaultBirthdayMessage = "Happy birthday, dear {{.FirstName}} {{.LastName}}!{{if .Age}} Congratulations on turning {{.Age}}!{{end}}"
+
+const defaultBelatedTitle = "Happy Belated Birthday"
+
+const defaultBelatedMessage = "Happy belated birthday, dear {{.FirstName}} {{.LastName}}! Sorry we missed your birthday on {{.Date}}."
+
+// Milestone selects a special title and message template for some ages. It
+// matches when the age turned is listed in Ages, or when Every is set and the
+// age is a multiple of it.
+type Milestone struct {
+	Ages     []int
+	Every    int
+	Title    string
+	Template string
+}
+
+type MessageOptions struct {
+	Variants        []MessageVariant
+	Milestones      []Milestone
+	BelatedTitle    string
+	BelatedTemplate string
+	EventTemplates  map[EventType]EventTemplate
+}
+
+type greetingData struct {
+	FirstName string
+	LastName  string
+	Age       int
+	Date      string
+}
+
+// BuildBirthdayMessageOn builds the greeting sent on date, taking the age the
+// friend is turning into account. Friends without a known birth year get a
+// message without an age. With variants, the wording is picked per friend and
+// year; milestones still take precedence.
+func (friend Friend) BuildBirthdayMessageOn(date time.Time, options MessageOptions) (BirthdayGreetings, error) {
+	return friend.buildBirthdayMessage(date, date, false, options)
+}
+
+// buildBirthdayMessage builds the greeting for the birthday on celebration
+// that is delivered on sendDay. Greetings delivered on another day mention
+// the real date, and belated greetings use the belated template.
+func (friend Friend) buildBirthdayMessage(celebration time.Time, sendDay time.Time, belated bool, options MessageOptions) (BirthdayGreetings, error) {
+	if err := friend.validate(); err != nil {
+		return BirthdayGreetings{}, err
+	}
+
+	birth, err := parseBirthDate(friend.BirthDate)
+	if err != nil {
+		return BirthdayGreetings{}, err
+	}
+
+	data := greetingData{FirstName: friend.FirstName, LastName: friend.LastName, Age: birth.ageOn(celebration), Date: celebration.Format("Monday, January 2")}
+	if birth.hasYear() && birth.year > celebration.Year() {
+		return BirthdayGreetings{}, errors.New("birth date is in the future")
+	}
+
+	title, message := defaultBirthdayTitle, defaultBirthdayMessage
+	if template, found := options.EventTemplates[Birthday]; found {
+		title = orDefault(template.Title, title)
+		message = orDefault(template.Template, message)
+	}
+
+	if err := checkVariants(options.Variants); err != nil {
+		return BirthdayGreetings{}, err
+	}
+
+	if index := pickVariant(options.Variants, friend.identity(), celebration.Year()); index >= 0 {
+		variant := options.Variants[index]
+		title = orDefault(variant.Title, title)
+		message = orDefault(variant.Template, message)
+	}
+
+	if belated {
+		title = orDefault(options.BelatedTitle, defaultBelatedTitle)
+		message = orDefault(options.BelatedTemplate, defaultBelatedMessage)
+	} else if milestone, found := findMilestone(options.Milestones, data.Age); found {
+		title = orDefault(milestone.Title, title)
+		message = orDefault(milestone.Template, message)
+	}
+
+	renderedTitle, err := renderTemplate("birthday title", title, data)
+	if err != nil {
+		return BirthdayGreetings{}, err
+	}
+
+	renderedMessage, err := renderTemplate("birthday message", message, data)
+	if err != nil {
+		return BirthdayGreetings{}, err
+	}
+
+	if !belated && !sameDay(celebration, sendDay) {
+		renderedMessage += fmt.Sprintf(" Your birthday is on %s.", data.Date)
+	}
+
+	return BirthdayGreetings{title: renderedTitle, message: renderedMessage}, nil
+}
+
+func findMilestone(milestones []Milestone, age int) (Milestone, bool) {
+	if age <= 0 {
+		return Milestone{}, false
+	}
+
+	for _, milestone := range milestones {
+		for _, milestoneAge := range milestone.Ages {
+			if milestoneAge == age {
+				return milestone, true
+			}
+		}
+
+		if milestone.Every > 0 && age%milestone.Every == 0 {
+			return milestone, true
+		}
+	}
+
+	return Milestone{}, false
+}
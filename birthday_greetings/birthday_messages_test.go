@@ -0,0 +1,93 @@
+package birthday_greetings
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func milestones() []Milestone {
+	return []Milestone{
+		{Ages: []int{18}, Title: "Welcome to adulthood", Template: "Happy 18th birthday, {{.FirstName}}!"},
+		{Every: 10, Title: "A new decade", Template: "{{.Age}} looks great on you, {{.FirstName}}!"},
+	}
+}
+
+func TestBuildBirthdayMessageOnMentionsAge(t *testing.T) {
+	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "1990/05/15", Email: "jane.smith@example.com"}
+	want := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear Jane Doe! Congratulations on turning 34!"}
+
+	got, err := friend.BuildBirthdayMessageOn(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), MessageOptions{Milestones: milestones()})
+	if err != nil {
+		t.Errorf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected '%v' but got '%v'", want, got)
+	}
+}
+
+func TestBuildBirthdayMessageOnMilestoneAge(t *testing.T) {
+	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "2006/05/15", Email: "jane.smith@example.com"}
+	want := BirthdayGreetings{title: "Welcome to adulthood", message: "Happy 18th birthday, Jane!"}
+
+	got, err := friend.BuildBirthdayMessageOn(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), MessageOptions{Milestones: milestones()})
+	if err != nil {
+		t.Errorf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected '%v' but got '%v'", want, got)
+	}
+}
+
+func TestBuildBirthdayMessageOnEveryDecade(t *testing.T) {
+	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "1974/05/15", Email: "jane.smith@example.com"}
+	want := BirthdayGreetings{title: "A new decade", message: "50 looks great on you, Jane!"}
+
+	got, err := friend.BuildBirthdayMessageOn(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), MessageOptions{Milestones: milestones()})
+	if err != nil {
+		t.Errorf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected '%v' but got '%v'", want, got)
+	}
+}
+
+func TestBuildBirthdayMessageOnWithoutBirthYear(t *testing.T) {
+	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "05/15", Email: "jane.smith@example.com"}
+	want := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear Jane Doe!"}
+
+	got, err := friend.BuildBirthdayMessageOn(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), MessageOptions{Milestones: milestones()})
+	if err != nil {
+		t.Errorf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected '%v' but got '%v'", want, got)
+	}
+}
+
+func TestBuildBirthdayMessageOnWithoutFriendEmail(t *testing.T) {
+	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "1990/05/15", Email: ""}
+
+	_, err := friend.BuildBirthdayMessageOn(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), MessageOptions{})
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	if err.Error() != "email is empty" {
+		t.Errorf("Expected error message to be 'email is empty' but got '%v'", err.Error())
+	}
+}
+
+func TestBuildBirthdayMessageOnBeforeBirth(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "2030/05/15", Email: "john.doe@foobar.com"}
+
+	_, err := friend.BuildBirthdayMessageOn(time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), MessageOptions{})
+	if err == nil || err.Error() != "birth date is in the future" {
+		t.Errorf("Expected a future birth date error but got '%v'", err)
+	}
+}
@@ -0,0 +1,140 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CachingFriendsRepository keeps the friends of repo, which reads the file
+// at path, until the file changes.
+type CachingFriendsRepository struct {
+	repo    FriendsRepository
+	path    string
+	mu      sync.RWMutex
+	loaded  bool
+	friends []Friend
+	modTime time.Time
+	hash    []byte
+}
+
+func NewCachingFriendsRepository(repo FriendsRepository, path string) *CachingFriendsRepository {
+	return &CachingFriendsRepository{repo: repo, path: path}
+}
+
+func (cache *CachingFriendsRepository) GetFriends() ([]Friend, error) {
+	info, err := os.Stat(cache.path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.RLock()
+	if cache.loaded && info.ModTime().Equal(cache.modTime) {
+		friends := cache.snapshot()
+		cache.mu.RUnlock()
+		return friends, nil
+	}
+	cache.mu.RUnlock()
+
+	if err := cache.Refresh(); err != nil {
+		return nil, err
+	}
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return cache.snapshot(), nil
+}
+
+// friendsFileParser is a repository that reads friends from the content of
+// its file, so that the cache parses exactly the bytes it hashed.
+type friendsFileParser interface {
+	parseFriendsFile(data []byte) ([]Friend, error)
+}
+
+// Refresh reloads the wrapped repository when the file content has changed
+// since the last load. A changed modification time with identical content
+// only updates the recorded modification time. Other repositories than the
+// text file and encrypted ones load the file themselves, after it was
+// hashed, so a write in between is picked up by the next refresh.
+func (cache *CachingFriendsRepository) Refresh() error {
+	info, err := os.Stat(cache.path)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(cache.path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.loaded && bytes.Equal(sum[:], cache.hash) {
+		cache.modTime = info.ModTime()
+		return nil
+	}
+
+	var friends []Friend
+	if parser, ok := cache.repo.(friendsFileParser); ok {
+		friends, err = parser.parseFriendsFile(content)
+	} else {
+		friends, err = cache.repo.GetFriends()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cache.friends = friends
+	cache.modTime = info.ModTime()
+	cache.hash = sum[:]
+	cache.loaded = true
+	return nil
+}
+
+// Watch polls the file every interval and refreshes the cache until stop is
+// closed. Errors are reported on the returned channel, which is closed once
+// watching stops.
+func (cache *CachingFriendsRepository) Watch(interval time.Duration, stop <-chan struct{}) <-chan error {
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := cache.Refresh(); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}
+	}()
+
+	return errs
+}
+
+// snapshot copies the cached friends, slices included, so callers cannot
+// change the cache.
+func (cache *CachingFriendsRepository) snapshot() []Friend {
+	friends := make([]Friend, len(cache.friends))
+	for i, friend := range cache.friends {
+		friend.Tags = slices.Clone(friend.Tags)
+		friend.Events = slices.Clone(friend.Events)
+		friends[i] = friend
+	}
+
+	return friends
+}
@@ -0,0 +1,144 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type countingFriendsRepository struct {
+	repo  FriendsRepository
+	mu    sync.Mutex
+	loads int
+}
+
+func (counter *countingFriendsRepository) GetFriends() ([]Friend, error) {
+	counter.mu.Lock()
+	counter.loads++
+	counter.mu.Unlock()
+	return counter.repo.GetFriends()
+}
+
+func writeFriendsFile(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestCachingFriendsRepositoryLoadsOnlyOnce(t *testing.T) {
+	counter := &countingFriendsRepository{repo: TextFileFriendsRepository{path: "birthdays.txt"}}
+	repository := NewCachingFriendsRepository(counter, "birthdays.txt")
+
+	for i := 0; i < 3; i++ {
+		friends, err := repository.GetFriends()
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if len(friends) != 2 {
+			t.Errorf("Expected 2 friends but got %d", len(friends))
+		}
+	}
+
+	if counter.loads != 1 {
+		t.Errorf("Expected 1 load but got %d", counter.loads)
+	}
+}
+
+func TestCachingFriendsRepositoryReloadsWhenContentChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeFriendsFile(t, path, "Doe, John, 1982/10/08, john.doe@foobar.com\n", start)
+	counter := &countingFriendsRepository{repo: TextFileFriendsRepository{path: path}}
+	repository := NewCachingFriendsRepository(counter, path)
+
+	if _, err := repository.GetFriends(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	writeFriendsFile(t, path, "Doe, John, 1982/10/08, john.doe@foobar.com\n", start.Add(time.Hour))
+	if _, err := repository.GetFriends(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if counter.loads != 1 {
+		t.Errorf("Expected unchanged content not to reload but got %d loads", counter.loads)
+	}
+
+	writeFriendsFile(t, path, "Doe, John, 1982/10/08, john.doe@foobar.com\nAnn, Mary, 1975/09/11, mary.ann@foobar.com\n", start.Add(2*time.Hour))
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if counter.loads != 2 || len(friends) != 2 {
+		t.Errorf("Expected a reload with 2 friends but got %d loads and %d friends", counter.loads, len(friends))
+	}
+}
+
+func TestCachingFriendsRepositoryWatchRefreshesInBackground(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeFriendsFile(t, path, "Doe, John, 1982/10/08, john.doe@foobar.com\n", start)
+	counter := &countingFriendsRepository{repo: TextFileFriendsRepository{path: path}}
+	repository := NewCachingFriendsRepository(counter, path)
+	stop := make(chan struct{})
+	errs := repository.Watch(time.Millisecond, stop)
+
+	writeFriendsFile(t, path, "Ann, Mary, 1975/09/11, mary.ann@foobar.com\n", start.Add(time.Hour))
+	deadline := time.Now().Add(time.Second)
+	for {
+		repository.mu.RLock()
+		loaded := repository.loaded && repository.friends[0].FirstName == "Mary"
+		repository.mu.RUnlock()
+		if loaded || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	for range errs {
+	}
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if friends[0].FirstName != "Mary" {
+		t.Errorf("Expected watcher to load 'Mary' but got '%s'", friends[0].FirstName)
+	}
+}
+
+func TestCachingFriendsRepositoryWithMissingFile(t *testing.T) {
+	repository := NewCachingFriendsRepository(TextFileFriendsRepository{path: "invalid.txt"}, "invalid.txt")
+
+	_, err := repository.GetFriends()
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestCachingFriendsRepositoryReturnsCopies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	writeFriendsFile(t, path, "last_name,first_name,birth_date,email,tags,name_day\nDoe,John,1982/10/08,john.doe@foobar.com,work,07/06\n", time.Now())
+	repository := NewCachingFriendsRepository(TextFileFriendsRepository{path: path}, path)
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	friends[0].Tags[0] = "family"
+	friends[0].Events[0].Date = "01/01"
+
+	again, _ := repository.GetFriends()
+	if again[0].Tags[0] != "work" || again[0].Events[0].Date != "07/06" {
+		t.Errorf("Expected the cache to be unchanged but got %v", again[0])
+	}
+}
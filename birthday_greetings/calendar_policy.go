@@ -0,0 +1,112 @@
+package birthday_greetings
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+type ShiftRule int
+
+const (
+	SendOnExactDay ShiftRule = iota
+	ShiftToPreviousBusinessDay
+	ShiftToNextBusinessDay
+)
+
+// calendarSearchDays bounds how far a greeting can be shifted, which is more
+// than any realistic run of weekends and holidays.
+const calendarSearchDays = 14
+
+// CalendarPolicy decides on which day a greeting is delivered. Weekend and
+// holiday birthdays move to the previous Friday or next Monday, or to the
+// nearest business day when that day is a holiday too.
+type CalendarPolicy struct {
+	rule     ShiftRule
+	holidays map[string]bool
+}
+
+func NewCalendarPolicy(rule ShiftRule, holidays ...time.Time) CalendarPolicy {
+	policy := CalendarPolicy{rule: rule, holidays: map[string]bool{}}
+	for _, holiday := range holidays {
+		policy.holidays[holiday.Format(birthDateLayout)] = true
+	}
+
+	return policy
+}
+
+// LoadHolidays reads one "YYYY/MM/DD" date per line, skipping blank lines and
+// lines starting with "#".
+func LoadHolidays(path string) ([]time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	holidays := []time.Time{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		holiday, err := time.Parse(birthDateLayout, line)
+		if err != nil {
+			return nil, errors.New("invalid holiday date: " + line)
+		}
+
+		holidays = append(holidays, holiday)
+	}
+
+	return holidays, scanner.Err()
+}
+
+func (policy CalendarPolicy) isBusinessDay(date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+
+	return !policy.holidays[date.Format(birthDateLayout)]
+}
+
+// SendDate returns the day on which a greeting for date is delivered.
+func (policy CalendarPolicy) SendDate(date time.Time) time.Time {
+	step := 0
+	switch policy.rule {
+	case ShiftToPreviousBusinessDay:
+		step = -1
+	case ShiftToNextBusinessDay:
+		step = 1
+	default:
+		return date
+	}
+
+	for i := 0; i < calendarSearchDays && !policy.isBusinessDay(date); i++ {
+		date = date.AddDate(0, 0, step)
+	}
+
+	return date
+}
+
+// CelebrationDates returns every date whose greeting is delivered on sendDay,
+// in chronological order.
+func (policy CalendarPolicy) CelebrationDates(sendDay time.Time) []time.Time {
+	dates := []time.Time{}
+	for offset := -calendarSearchDays; offset <= calendarSearchDays; offset++ {
+		date := sendDay.AddDate(0, 0, offset)
+		if sameDay(policy.SendDate(date), sendDay) {
+			dates = append(dates, date)
+		}
+	}
+
+	return dates
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
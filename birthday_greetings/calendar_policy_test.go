@@ -0,0 +1,117 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCalendarPolicyShiftsWeekendToPreviousFriday(t *testing.T) {
+	policy := NewCalendarPolicy(ShiftToPreviousBusinessDay)
+	sunday := time.Date(2023, 10, 8, 0, 0, 0, 0, time.UTC)
+
+	got := policy.SendDate(sunday)
+
+	if !sameDay(got, time.Date(2023, 10, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Friday October 6 but got %v", got)
+	}
+}
+
+func TestCalendarPolicyShiftsWeekendAndHolidayToNextBusinessDay(t *testing.T) {
+	policy := NewCalendarPolicy(ShiftToNextBusinessDay, time.Date(2023, 10, 9, 0, 0, 0, 0, time.UTC))
+	sunday := time.Date(2023, 10, 8, 0, 0, 0, 0, time.UTC)
+
+	got := policy.SendDate(sunday)
+
+	if !sameDay(got, time.Date(2023, 10, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Tuesday October 10 but got %v", got)
+	}
+}
+
+func TestCalendarPolicyOnExactDayKeepsWeekends(t *testing.T) {
+	policy := NewCalendarPolicy(SendOnExactDay)
+	sunday := time.Date(2023, 10, 8, 0, 0, 0, 0, time.UTC)
+
+	if got := policy.SendDate(sunday); !sameDay(got, sunday) {
+		t.Errorf("Expected Sunday October 8 but got %v", got)
+	}
+}
+
+func TestCalendarPolicyCelebrationDatesOnFriday(t *testing.T) {
+	policy := NewCalendarPolicy(ShiftToPreviousBusinessDay)
+	friday := time.Date(2023, 10, 6, 0, 0, 0, 0, time.UTC)
+
+	dates := policy.CelebrationDates(friday)
+
+	if len(dates) != 3 || !sameDay(dates[0], friday) || !sameDay(dates[2], time.Date(2023, 10, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Friday to cover Friday through Sunday but got %v", dates)
+	}
+
+	if got := policy.CelebrationDates(time.Date(2023, 10, 7, 0, 0, 0, 0, time.UTC)); len(got) != 0 {
+		t.Errorf("Expected nothing to be sent on Saturday but got %v", got)
+	}
+}
+
+func TestLoadHolidays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.txt")
+	if err := os.WriteFile(path, []byte("# Public holidays\n2023/12/25\n\n2024/01/01\n"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	holidays, err := LoadHolidays(path)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(holidays) != 2 {
+		t.Errorf("Expected 2 holidays but got %d", len(holidays))
+	}
+}
+
+func TestLoadHolidaysWithInvalidDate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.txt")
+	if err := os.WriteFile(path, []byte("25/12/2023\n"), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err := LoadHolidays(path)
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestShiftedGreetingMentionsRealDate(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	sunday := time.Date(2023, 10, 8, 0, 0, 0, 0, time.UTC)
+	friday := time.Date(2023, 10, 6, 0, 0, 0, 0, time.UTC)
+	want := "Happy birthday, dear John Doe! Congratulations on turning 41! Your birthday is on Sunday, October 8."
+
+	got, err := friend.buildBirthdayMessage(sunday, friday, false, MessageOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if got.message != want {
+		t.Errorf("Expected '%s' but got '%s'", want, got.message)
+	}
+}
+
+func TestShiftedEventGreetingMentionsRealDate(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com", Events: []Event{{Type: WorkAnniversary, Date: "2015/03/02"}}},
+	}}
+	scheduler := NewScheduler(repository, SchedulerConfig{Calendar: NewCalendarPolicy(ShiftToPreviousBusinessDay)})
+	want := "Happy work anniversary, dear John Doe! 9 years already! Your work anniversary is on Saturday, March 2."
+
+	// Friday, March 1.
+	greetings, err := scheduler.Plan(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(greetings) != 1 || greetings[0].message != want {
+		t.Errorf("Expected '%s' but got %v", want, greetings)
+	}
+}
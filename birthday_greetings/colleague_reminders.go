@@ -0,0 +1,166 @@
+package birthday_greetings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const ColleagueReminderKind = "colleague_reminder"
+
+// ColleagueReminderConfig makes the scheduler tell every friend about the
+// other friends' birthdays. When Grouped is true, each friend gets a single
+// reminder naming every celebrant.
+type ColleagueReminderConfig struct {
+	Enabled bool
+	Grouped bool
+}
+
+// BuildColleagueReminders tells everyone in friends about today's birthdays.
+// Celebrants never receive a reminder about their own birthday. When grouped
+// is true, each recipient gets a single reminder naming every celebrant.
+func BuildColleagueReminders(friends []Friend, today time.Time, grouped bool) ([]BirthdayGreetings, error) {
+	reminders, err := buildColleagueReminders(friends, today, grouped)
+	if err != nil {
+		return nil, err
+	}
+
+	greetings := make([]BirthdayGreetings, len(reminders))
+	for i, reminder := range reminders {
+		greetings[i] = reminder.greetings
+	}
+
+	return greetings, nil
+}
+
+// buildColleagueReminders returns each reminder along with the friend it is
+// sent to.
+func buildColleagueReminders(friends []Friend, today time.Time, grouped bool) ([]scheduledGreeting, error) {
+	celebrants := []Friend{}
+	for _, friend := range friends {
+		isBirthday, err := friend.IsBirthdayOn(today)
+		if err != nil {
+			return nil, err
+		}
+
+		if isBirthday {
+			celebrants = append(celebrants, friend)
+		}
+	}
+
+	reminders := []scheduledGreeting{}
+	if len(celebrants) == 0 {
+		return reminders, nil
+	}
+
+	for _, recipient := range friends {
+		if recipient.Email == "" {
+			return nil, errors.New("email is empty")
+		}
+
+		others := []Friend{}
+		for _, celebrant := range celebrants {
+			if !sameFriendData(celebrant, recipient) {
+				others = append(others, celebrant)
+			}
+		}
+
+		if len(others) == 0 {
+			continue
+		}
+
+		if grouped {
+			reminders = append(reminders, scheduledGreeting{friend: recipient, greetings: buildColleagueReminder(recipient, others, today)})
+			continue
+		}
+
+		for _, celebrant := range others {
+			reminders = append(reminders, scheduledGreeting{friend: recipient, greetings: buildColleagueReminder(recipient, []Friend{celebrant}, today)})
+		}
+	}
+
+	return reminders, nil
+}
+
+// buildColleagueReminder derives the message ID from the date, the recipient
+// and the celebrants, so the history tells whether the reminder was sent.
+func buildColleagueReminder(recipient Friend, celebrants []Friend, today time.Time) BirthdayGreetings {
+	names := make([]string, len(celebrants))
+	ids := make([]string, len(celebrants))
+	for i, celebrant := range celebrants {
+		names[i] = fmt.Sprintf("%s %s's", celebrant.FirstName, celebrant.LastName)
+		ids[i] = celebrant.identity()
+	}
+
+	message := fmt.Sprintf("Today is %s birthday, don't forget to send a message!", names[0])
+	if len(names) > 1 {
+		message = fmt.Sprintf("Today is %s and %s birthday, don't forget to send them a message!", strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
+	}
+
+	sum := sha256.Sum256([]byte(today.Format(birthDateLayout) + "\x00" + recipient.identity() + "\x00" + strings.Join(ids, "\x00")))
+
+	return BirthdayGreetings{
+		title:     "Birthday Reminder",
+		message:   message,
+		recipient: recipient.Email,
+		messageID: "colleague-" + hex.EncodeToString(sum[:12]) + "@birthday-greetings",
+		kind:      ColleagueReminderKind,
+		mentions:  ids,
+	}
+}
+
+// colleagueReminders plans the reminders about the birthdays on the local
+// date at now in the scheduler's time zone. Each reminder belongs to the
+// friend it is sent to and mentions the celebrants. Friends who opted out
+// get no reminder, and reminders already in the history or held for delivery
+// are not planned again.
+func (scheduler *Scheduler) colleagueReminders(friends []Friend, now time.Time) ([]scheduledGreeting, error) {
+	config := scheduler.config.ColleagueReminders
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	today, err := scheduler.localTime(Friend{}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	reminders, err := buildColleagueReminders(FilterFriends(friends, scheduler.config.Tags), today, config.Grouped)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := []scheduledGreeting{}
+	errs := []error{}
+	for _, reminder := range reminders {
+		recipient := reminder.friend
+
+		if optedOut, err := scheduler.isOptedOut(recipient); err != nil || optedOut {
+			errs = append(errs, err)
+			continue
+		}
+
+		if sent, err := scheduler.isPlannedOrSent(reminder.greetings.messageID); err != nil || sent {
+			errs = append(errs, err)
+			continue
+		}
+
+		local, err := scheduler.localTime(recipient, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		reminder.local = local
+		if scheduler.config.Unsubscribe != nil {
+			reminder.greetings.headers = scheduler.config.Unsubscribe.Headers(recipient.identity())
+		}
+
+		planned = append(planned, reminder)
+	}
+
+	return planned, errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package birthday_greetings
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func reminderFriends() []Friend {
+	return []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/08", Email: "mary.ann@foobar.com"},
+		{FirstName: "Jane", LastName: "Smith", BirthDate: "1990/05/15", Email: "jane.smith@example.com"},
+	}
+}
+
+type reminderSummary struct {
+	recipient string
+	title     string
+	message   string
+}
+
+func summarizeReminders(reminders []BirthdayGreetings) []reminderSummary {
+	summaries := make([]reminderSummary, len(reminders))
+	for i, reminder := range reminders {
+		summaries[i] = reminderSummary{recipient: reminder.recipient, title: reminder.title, message: reminder.message}
+	}
+
+	return summaries
+}
+
+func TestBuildColleagueRemindersSendsOneReminderPerBirthday(t *testing.T) {
+	today := time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC)
+	want := []reminderSummary{
+		{recipient: "john.doe@foobar.com", title: "Birthday Reminder", message: "Today is Mary Ann's birthday, don't forget to send a message!"},
+		{recipient: "mary.ann@foobar.com", title: "Birthday Reminder", message: "Today is John Doe's birthday, don't forget to send a message!"},
+		{recipient: "jane.smith@example.com", title: "Birthday Reminder", message: "Today is John Doe's birthday, don't forget to send a message!"},
+		{recipient: "jane.smith@example.com", title: "Birthday Reminder", message: "Today is Mary Ann's birthday, don't forget to send a message!"},
+	}
+
+	reminders, err := BuildColleagueReminders(reminderFriends(), today, false)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if got := summarizeReminders(reminders); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v but got %v", want, got)
+	}
+}
+
+func TestBuildColleagueRemindersGroupedBySharedBirthday(t *testing.T) {
+	today := time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC)
+	want := []reminderSummary{
+		{recipient: "john.doe@foobar.com", title: "Birthday Reminder", message: "Today is Mary Ann's birthday, don't forget to send a message!"},
+		{recipient: "mary.ann@foobar.com", title: "Birthday Reminder", message: "Today is John Doe's birthday, don't forget to send a message!"},
+		{recipient: "jane.smith@example.com", title: "Birthday Reminder", message: "Today is John Doe's and Mary Ann's birthday, don't forget to send them a message!"},
+	}
+
+	reminders, err := BuildColleagueReminders(reminderFriends(), today, true)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if got := summarizeReminders(reminders); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v but got %v", want, got)
+	}
+}
+
+func TestBuildColleagueRemindersWithoutBirthdays(t *testing.T) {
+	today := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	reminders, err := BuildColleagueReminders(reminderFriends(), today, false)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(reminders) != 0 {
+		t.Errorf("Expected no reminders but got %d", len(reminders))
+	}
+}
+
+func TestSchedulerSendsColleagueRemindersOncePerDay(t *testing.T) {
+	optOuts := NewMemoryOptOutStore()
+	scheduler := NewScheduler(stubFriendsRepository{friends: reminderFriends()}, SchedulerConfig{
+		OptOuts:            optOuts,
+		ColleagueReminders: ColleagueReminderConfig{Enabled: true, Grouped: true},
+	})
+	jane := reminderFriends()[2]
+	optOuts.OptOut(jane.identity())
+
+	for range 2 {
+		if err := scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{})
+	reminders := []Receipt{}
+	for _, receipt := range receipts {
+		if receipt.Kind == ColleagueReminderKind {
+			reminders = append(reminders, receipt)
+		}
+	}
+
+	if len(reminders) != 2 {
+		t.Fatalf("Expected a reminder each for John and Mary but got %v", reminders)
+	}
+
+	john := reminderFriends()[0]
+	about, _ := scheduler.History().Query(HistoryQuery{FriendID: john.identity()})
+	if len(about) != 3 {
+		t.Errorf("Expected John's greeting and both reminders to concern John but got %v", about)
+	}
+}
@@ -0,0 +1,633 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config declares the whole greeting pipeline in a YAML file. Secret values
+// (the SMTP passwords and the unsubscribe key) written exactly as "${NAME}"
+// are read from the NAME environment variable; any other value, including
+// one containing "$", is used as is.
+type Config struct {
+	Friends       FriendsConfig      `yaml:"friends"`
+	Sender        SenderConfig       `yaml:"sender"`
+	Templates     TemplatesConfig    `yaml:"templates"`
+	Locale        LocaleConfig       `yaml:"locale"`
+	TimeZone      TimeZoneConfig     `yaml:"time_zone"`
+	Retry         RetryConfig        `yaml:"retry"`
+	GiftReminders GiftReminderConfig `yaml:"gift_reminders"`
+	Unsubscribe   UnsubscribeConfig  `yaml:"unsubscribe"`
+	Groups        []GroupConfig      `yaml:"groups"`
+	History       string             `yaml:"history"`
+	OptOuts       string             `yaml:"opt_outs"`
+	RunMarker     string             `yaml:"run_marker"`
+}
+
+// FriendsConfig lists the friends files. With a key file or key environment
+// variable, the files are read as encrypted friends files; otherwise they are
+// read in the given encoding and delimiter. Tags limits greetings to the
+// friends matching a tag expression.
+type FriendsConfig struct {
+	Sources   []string `yaml:"sources"`
+	Conflict  string   `yaml:"conflict"`
+	KeyFile   string   `yaml:"key_file"`
+	KeyEnv    string   `yaml:"key_env"`
+	Encoding  string   `yaml:"encoding"`
+	Delimiter string   `yaml:"delimiter"`
+	Tags      string   `yaml:"tags"`
+}
+
+type SenderConfig struct {
+	Type    string        `yaml:"type"`
+	From    string        `yaml:"from"`
+	SMTP    SMTPConfig    `yaml:"smtp"`
+	DKIM    DKIMConfig    `yaml:"dkim"`
+	Mbox    string        `yaml:"mbox"`
+	Maildir string        `yaml:"maildir"`
+	Archive ArchiveConfig `yaml:"archive"`
+}
+
+type SMTPConfig struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type DKIMConfig struct {
+	Domain                 string   `yaml:"domain"`
+	Selector               string   `yaml:"selector"`
+	KeyFile                string   `yaml:"key_file"`
+	Headers                []string `yaml:"headers"`
+	HeaderCanonicalization string   `yaml:"header_canonicalization"`
+	BodyCanonicalization   string   `yaml:"body_canonicalization"`
+}
+
+type ArchiveConfig struct {
+	Mbox    string `yaml:"mbox"`
+	Maildir string `yaml:"maildir"`
+}
+
+type TemplateConfig struct {
+	Title    string `yaml:"title"`
+	Template string `yaml:"template"`
+}
+
+type MilestoneConfig struct {
+	Ages     []int  `yaml:"ages"`
+	Every    int    `yaml:"every"`
+	Title    string `yaml:"title"`
+	Template string `yaml:"template"`
+}
+
+// VariantConfig is a message variant. Weight defaults to 1, and a weight of
+// 0 disables the variant.
+type VariantConfig struct {
+	Weight   *int   `yaml:"weight"`
+	Title    string `yaml:"title"`
+	Template string `yaml:"template"`
+}
+
+type TemplatesConfig struct {
+	Birthday   TemplateConfig               `yaml:"birthday"`
+	Variants   []VariantConfig              `yaml:"variants"`
+	Belated    TemplateConfig               `yaml:"belated"`
+	Events     map[EventType]TemplateConfig `yaml:"events"`
+	Milestones []MilestoneConfig            `yaml:"milestones"`
+}
+
+// LocaleConfig holds the defaults for friends without their own settings and
+// the local business calendar.
+type LocaleConfig struct {
+	TimeZone string `yaml:"time_zone"`
+	Holidays string `yaml:"holidays"`
+	Weekends string `yaml:"weekends"`
+}
+
+type TimeZoneConfig struct {
+	SendWindow     string `yaml:"send_window"`
+	MaxCatchUpDays int    `yaml:"max_catch_up_days"`
+}
+
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+}
+
+// GroupConfig gives the friends matching Tags their own templates and
+// sender. Groups are tried in order and the first match wins.
+type GroupConfig struct {
+	Name      string           `yaml:"name"`
+	Tags      string           `yaml:"tags"`
+	Templates *TemplatesConfig `yaml:"templates"`
+	Sender    *SenderConfig    `yaml:"sender"`
+}
+
+type UnsubscribeConfig struct {
+	Key     string `yaml:"key"`
+	BaseURL string `yaml:"base_url"`
+}
+
+var conflictRules = map[string]ConflictRule{
+	"":           FirstWins,
+	"first_wins": FirstWins,
+	"last_wins":  LastWins,
+	"error":      ErrorOnConflict,
+}
+
+var shiftRules = map[string]ShiftRule{
+	"":                      SendOnExactDay,
+	"exact_day":             SendOnExactDay,
+	"previous_business_day": ShiftToPreviousBusinessDay,
+	"next_business_day":     ShiftToNextBusinessDay,
+}
+
+// LoadConfig reads and validates a config file. Unknown keys are rejected so
+// that typos do not silently fall back to defaults.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := config.expandSecrets(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+func (config *Config) expandSecrets() error {
+	errs := []error{}
+	expand := func(field string, value *string) {
+		if !strings.HasPrefix(*value, "${") || !strings.HasSuffix(*value, "}") || len(*value) <= 3 {
+			return
+		}
+
+		name := (*value)[2 : len(*value)-1]
+
+		secret, found := os.LookupEnv(name)
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: environment variable %s is not set", field, name))
+		}
+		*value = secret
+	}
+
+	expand("sender.smtp.password", &config.Sender.SMTP.Password)
+	expand("unsubscribe.key", &config.Unsubscribe.Key)
+
+	for i, group := range config.Groups {
+		if group.Sender != nil {
+			expand(fmt.Sprintf("groups[%d].sender.smtp.password", i), &group.Sender.SMTP.Password)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting at once, each prefixed with its key.
+func (config Config) Validate() error {
+	errs := []error{}
+	invalid := func(field string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf(field+": "+format, args...))
+	}
+
+	if len(config.Friends.Sources) == 0 {
+		invalid("friends.sources", "at least one source is required")
+	}
+
+	if config.Friends.KeyFile != "" && config.Friends.KeyEnv != "" {
+		invalid("friends", "key_file and key_env cannot both be set")
+	}
+
+	if _, err := config.Friends.textFileOptions().encoding(); err != nil {
+		invalid("friends.encoding", "%v", err)
+	}
+
+	if utf8.RuneCountInString(config.Friends.Delimiter) > 1 {
+		invalid("friends.delimiter", "must be a single character")
+	}
+
+	if _, found := conflictRules[config.Friends.Conflict]; !found {
+		invalid("friends.conflict", "unknown rule %q, expected first_wins, last_wins or error", config.Friends.Conflict)
+	}
+
+	if config.Friends.Tags != "" {
+		if _, err := ParseTagExpression(config.Friends.Tags); err != nil {
+			invalid("friends.tags", "%v", err)
+		}
+	}
+
+	config.Sender.validate("sender", invalid)
+	config.Templates.validate("templates", invalid)
+
+	for i, group := range config.Groups {
+		field := fmt.Sprintf("groups[%d]", i)
+		if group.Name == "" {
+			invalid(field+".name", "is required")
+		}
+
+		if _, err := ParseTagExpression(group.Tags); err != nil {
+			invalid(field+".tags", "%v", err)
+		}
+
+		if group.Sender != nil {
+			group.Sender.validate(field+".sender", invalid)
+		}
+
+		if group.Templates != nil {
+			group.Templates.validate(field+".templates", invalid)
+		}
+	}
+
+	if config.Locale.TimeZone != "" {
+		if _, err := time.LoadLocation(config.Locale.TimeZone); err != nil {
+			invalid("locale.time_zone", "unknown time zone %q", config.Locale.TimeZone)
+		}
+	}
+
+	if _, found := shiftRules[config.Locale.Weekends]; !found {
+		invalid("locale.weekends", "unknown rule %q, expected exact_day, previous_business_day or next_business_day", config.Locale.Weekends)
+	}
+
+	if config.TimeZone.SendWindow != "" {
+		if _, err := ParseSendWindow(config.TimeZone.SendWindow); err != nil {
+			invalid("time_zone.send_window", "%v", err)
+		}
+	}
+
+	if config.TimeZone.MaxCatchUpDays < 0 {
+		invalid("time_zone.max_catch_up_days", "cannot be negative")
+	}
+
+	if config.GiftReminders.LeadDays < 0 {
+		invalid("gift_reminders.lead_days", "cannot be negative")
+	}
+
+	if config.GiftReminders.LeadDays > 0 && len(config.GiftReminders.Recipients) == 0 {
+		invalid("gift_reminders.recipients", "at least one recipient is required")
+	}
+
+	if config.GiftReminders.Tags != "" {
+		if _, err := ParseTagExpression(config.GiftReminders.Tags); err != nil {
+			invalid("gift_reminders.tags", "%v", err)
+		}
+	}
+
+	checkTemplate("gift_reminders", TemplateConfig{Title: config.GiftReminders.Title, Template: config.GiftReminders.Template}, giftReminderData{}, invalid)
+
+	if config.Retry.MaxAttempts < 0 {
+		invalid("retry.max_attempts", "cannot be negative")
+	}
+
+	if config.Retry.Backoff < 0 {
+		invalid("retry.backoff", "cannot be negative")
+	}
+
+	if (config.Unsubscribe.Key == "") != (config.Unsubscribe.BaseURL == "") {
+		invalid("unsubscribe", "key and base_url must be set together")
+	}
+
+	return errors.Join(errs...)
+}
+
+func (sender SenderConfig) validate(field string, invalid func(field string, format string, args ...any)) {
+	switch sender.Type {
+	case "", "noop":
+	case "smtp":
+		if sender.SMTP.Addr == "" {
+			invalid(field+".smtp.addr", "is required")
+		}
+	case "mbox":
+		if sender.Mbox == "" {
+			invalid(field+".mbox", "is required")
+		}
+	case "maildir":
+		if sender.Maildir == "" {
+			invalid(field+".maildir", "is required")
+		}
+	default:
+		invalid(field+".type", "unknown sender %q, expected noop, smtp, mbox or maildir", sender.Type)
+	}
+
+	needsFrom := sender.Type == "smtp" || sender.Type == "mbox" || sender.Type == "maildir" || sender.Archive.Mbox != "" || sender.Archive.Maildir != ""
+	if needsFrom && sender.From == "" {
+		invalid(field+".from", "is required")
+	}
+
+	if sender.DKIM.KeyFile != "" && sender.Type != "smtp" {
+		invalid(field+".dkim", "is only supported by the smtp sender")
+	}
+}
+
+func (config TemplatesConfig) validate(field string, invalid func(field string, format string, args ...any)) {
+	checkTemplate(field+".birthday", config.Birthday, greetingData{}, invalid)
+	checkTemplate(field+".belated", config.Belated, greetingData{}, invalid)
+
+	for eventType, template := range config.Events {
+		checkTemplate(field+".events."+string(eventType), template, greetingData{}, invalid)
+	}
+
+	for i, variant := range config.Variants {
+		checkTemplate(field+fmt.Sprintf(".variants[%d]", i), TemplateConfig{Title: variant.Title, Template: variant.Template}, greetingData{}, invalid)
+	}
+
+	for i, milestone := range config.Milestones {
+		checkTemplate(field+fmt.Sprintf(".milestones[%d]", i), TemplateConfig{Title: milestone.Title, Template: milestone.Template}, greetingData{}, invalid)
+	}
+
+	for eventType, template := range config.Events {
+		if _, found := defaultEventTemplates[eventType]; !found && eventType != Birthday {
+			invalid(field+".events", "unknown event type %q", eventType)
+		} else if eventType != Birthday && (template.Title == "" || template.Template == "") {
+			invalid(field+".events."+string(eventType), "title and template are required")
+		}
+	}
+
+	for i, variant := range config.Variants {
+		if variant.Weight != nil && *variant.Weight < 0 {
+			invalid(field+fmt.Sprintf(".variants[%d].weight", i), "cannot be negative")
+		}
+	}
+
+	for i, milestone := range config.Milestones {
+		if len(milestone.Ages) == 0 && milestone.Every <= 0 {
+			invalid(field+fmt.Sprintf(".milestones[%d]", i), "ages or every is required")
+		}
+	}
+}
+
+// checkTemplate renders the title and template with empty data, so that
+// syntax errors and unknown fields are reported before any greeting is due.
+func checkTemplate(field string, template TemplateConfig, data any, invalid func(field string, format string, args ...any)) {
+	if _, err := renderTemplate("title", template.Title, data); err != nil {
+		invalid(field+".title", "%v", err)
+	}
+
+	if _, err := renderTemplate("template", template.Template, data); err != nil {
+		invalid(field+".template", "%v", err)
+	}
+}
+
+// Scheduler wires the pipeline the config declares.
+func (config Config) Scheduler() (*Scheduler, error) {
+	repo, err := config.Repository()
+	if err != nil {
+		return nil, err
+	}
+
+	schedulerConfig, err := config.SchedulerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewScheduler(repo, schedulerConfig), nil
+}
+
+func (config Config) Repository() (FriendsRepository, error) {
+	if len(config.Friends.Sources) == 0 {
+		return nil, errors.New("friends.sources: at least one source is required")
+	}
+
+	var key []byte
+	var err error
+	switch {
+	case config.Friends.KeyFile != "":
+		key, err = LoadEncryptionKey(config.Friends.KeyFile)
+	case config.Friends.KeyEnv != "":
+		key, err = EncryptionKeyFromEnv(config.Friends.KeyEnv)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("friends: %w", err)
+	}
+
+	sources := make([]FriendsRepository, len(config.Friends.Sources))
+	for i, path := range config.Friends.Sources {
+		if key == nil {
+			sources[i] = NewTextFileFriendsRepository(path, config.Friends.textFileOptions())
+			continue
+		}
+
+		if sources[i], err = NewEncryptedFriendsRepository(path, key); err != nil {
+			return nil, fmt.Errorf("friends: %w", err)
+		}
+	}
+
+	if len(sources) == 1 {
+		return sources[0], nil
+	}
+
+	return NewMultiFriendsRepository(conflictRules[config.Friends.Conflict], sources...), nil
+}
+
+func (config FriendsConfig) textFileOptions() TextFileOptions {
+	options := TextFileOptions{Encoding: TextEncoding(config.Encoding)}
+	for _, delimiter := range config.Delimiter {
+		options.Delimiter = delimiter
+	}
+
+	return options
+}
+
+func (config Config) SchedulerConfig() (SchedulerConfig, error) {
+	if err := config.Validate(); err != nil {
+		return SchedulerConfig{}, err
+	}
+
+	schedulerConfig := SchedulerConfig{
+		Options:        config.Templates.messageOptions(),
+		MaxCatchUpDays: config.TimeZone.MaxCatchUpDays,
+		Retry:          RetryPolicy{MaxAttempts: config.Retry.MaxAttempts, Backoff: config.Retry.Backoff},
+		GiftReminders:  config.GiftReminders,
+	}
+
+	if config.TimeZone.SendWindow != "" {
+		schedulerConfig.Window, _ = ParseSendWindow(config.TimeZone.SendWindow)
+	}
+
+	if config.Locale.TimeZone != "" {
+		schedulerConfig.TimeZone, _ = time.LoadLocation(config.Locale.TimeZone)
+	}
+
+	holidays := []time.Time{}
+	if config.Locale.Holidays != "" {
+		loaded, err := LoadHolidays(config.Locale.Holidays)
+		if err != nil {
+			return SchedulerConfig{}, fmt.Errorf("locale.holidays: %w", err)
+		}
+		holidays = loaded
+	}
+	schedulerConfig.Calendar = NewCalendarPolicy(shiftRules[config.Locale.Weekends], holidays...)
+
+	sender, err := config.Sender.build()
+	if err != nil {
+		return SchedulerConfig{}, err
+	}
+	schedulerConfig.Sender = sender
+
+	if config.Friends.Tags != "" {
+		schedulerConfig.Tags, _ = ParseTagExpression(config.Friends.Tags)
+	}
+
+	for i, groupConfig := range config.Groups {
+		group, err := groupConfig.build()
+		if err != nil {
+			return SchedulerConfig{}, fmt.Errorf("groups[%d]: %w", i, err)
+		}
+		schedulerConfig.Groups = append(schedulerConfig.Groups, group)
+	}
+
+	if config.Unsubscribe.Key != "" {
+		signer, err := NewUnsubscribeSigner([]byte(config.Unsubscribe.Key), config.Unsubscribe.BaseURL)
+		if err != nil {
+			return SchedulerConfig{}, fmt.Errorf("unsubscribe: %w", err)
+		}
+		schedulerConfig.Unsubscribe = &signer
+	}
+
+	if config.History != "" {
+		schedulerConfig.History = NewFileHistoryStore(config.History)
+	}
+
+	if config.OptOuts != "" {
+		schedulerConfig.OptOuts = NewFileOptOutStore(config.OptOuts)
+	}
+
+	if config.RunMarker != "" {
+		schedulerConfig.Marker = NewFileRunMarker(config.RunMarker)
+	}
+
+	return schedulerConfig, nil
+}
+
+func (config GroupConfig) build() (FriendGroup, error) {
+	group := FriendGroup{Name: config.Name}
+	group.Tags, _ = ParseTagExpression(config.Tags)
+
+	if config.Templates != nil {
+		options := config.Templates.messageOptions()
+		group.Options = &options
+	}
+
+	if config.Sender != nil {
+		sender, err := config.Sender.build()
+		if err != nil {
+			return FriendGroup{}, err
+		}
+		group.Sender = sender
+	}
+
+	return group, nil
+}
+
+func (config SenderConfig) build() (Sender, error) {
+	var sender Sender = NoopSender{}
+
+	switch config.Type {
+	case "smtp":
+		var auth smtp.Auth
+		if config.SMTP.Username != "" {
+			host, _, _ := strings.Cut(config.SMTP.Addr, ":")
+			auth = smtp.PlainAuth("", config.SMTP.Username, config.SMTP.Password, host)
+		}
+
+		smtpSender := NewSMTPSender(config.SMTP.Addr, config.From, auth)
+		if config.DKIM.KeyFile != "" {
+			signer, err := config.DKIM.build()
+			if err != nil {
+				return nil, err
+			}
+			smtpSender.WithDKIM(signer)
+		}
+		sender = smtpSender
+	case "mbox":
+		sender = NewMboxSender(config.Mbox, config.From)
+	case "maildir":
+		sender = NewMaildirSender(config.Maildir, config.From)
+	}
+
+	archives := []Sender{}
+	if config.Archive.Mbox != "" {
+		archives = append(archives, NewMboxSender(config.Archive.Mbox, config.From))
+	}
+
+	if config.Archive.Maildir != "" {
+		archives = append(archives, NewMaildirSender(config.Archive.Maildir, config.From))
+	}
+
+	if len(archives) == 0 {
+		return sender, nil
+	}
+
+	return NewTeeSender(sender, archives...), nil
+}
+
+func (config DKIMConfig) build() (*DKIMSigner, error) {
+	key, err := LoadDKIMKey(config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("sender.dkim.key_file: %w", err)
+	}
+
+	signer, err := NewDKIMSigner(key, DKIMOptions{
+		Domain:                 config.Domain,
+		Selector:               config.Selector,
+		Headers:                config.Headers,
+		HeaderCanonicalization: Canonicalization(config.HeaderCanonicalization),
+		BodyCanonicalization:   Canonicalization(config.BodyCanonicalization),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sender.dkim: %w", err)
+	}
+
+	return signer, nil
+}
+
+func (config TemplatesConfig) messageOptions() MessageOptions {
+	options := MessageOptions{
+		BelatedTitle:    config.Belated.Title,
+		BelatedTemplate: config.Belated.Template,
+		EventTemplates:  map[EventType]EventTemplate{},
+	}
+
+	if config.Birthday != (TemplateConfig{}) {
+		options.EventTemplates[Birthday] = EventTemplate(config.Birthday)
+	}
+
+	for eventType, template := range config.Events {
+		options.EventTemplates[eventType] = EventTemplate(template)
+	}
+
+	for _, variant := range config.Variants {
+		weight := 1
+		if variant.Weight != nil {
+			weight = *variant.Weight
+		}
+		options.Variants = append(options.Variants, MessageVariant{Weight: weight, Title: variant.Title, Template: variant.Template})
+	}
+
+	for _, milestone := range config.Milestones {
+		options.Milestones = append(options.Milestones, Milestone(milestone))
+	}
+
+	return options
+}
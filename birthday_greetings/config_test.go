@@ -0,0 +1,257 @@
+package birthday_greetings
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "greetings.yaml")
+	os.WriteFile(path, []byte(content), 0o600)
+	return path
+}
+
+func TestLoadConfigReadsEverySection(t *testing.T) {
+	t.Setenv("SMTP_PASSWORD", "s3cret")
+	path := writeConfig(t, `
+friends:
+  sources: [birthdays.txt, colleagues.txt]
+  conflict: last_wins
+sender:
+  type: smtp
+  from: greetings@example.com
+  smtp:
+    addr: smtp.example.com:587
+    username: greetings
+    password: ${SMTP_PASSWORD}
+  archive:
+    mbox: greetings.mbox
+templates:
+  birthday:
+    title: Joyeux anniversaire
+  events:
+    name_day:
+      title: Bonne fête
+      template: Bonne fête, {{.FirstName}} !
+  milestones:
+    - every: 10
+      title: A round one!
+locale:
+  time_zone: Europe/Paris
+  weekends: next_business_day
+time_zone:
+  send_window: "08:00-20:00"
+  max_catch_up_days: 3
+retry:
+  max_attempts: 3
+  backoff: 1h
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if config.Sender.SMTP.Password != "s3cret" {
+		t.Errorf("Expected the password from the environment but got %v", config.Sender.SMTP.Password)
+	}
+
+	if config.Retry.Backoff != time.Hour || config.Retry.MaxAttempts != 3 {
+		t.Errorf("Expected the retry policy to load but got %v", config.Retry)
+	}
+
+	schedulerConfig, err := config.SchedulerConfig()
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if schedulerConfig.TimeZone.String() != "Europe/Paris" || schedulerConfig.MaxCatchUpDays != 3 || schedulerConfig.Window.Start != 8*time.Hour {
+		t.Errorf("Expected the time zone policy to load but got %v", schedulerConfig)
+	}
+
+	if _, ok := schedulerConfig.Sender.(TeeSender); !ok {
+		t.Errorf("Expected an archiving sender but got %T", schedulerConfig.Sender)
+	}
+
+	if schedulerConfig.Options.EventTemplates[Birthday].Title != "Joyeux anniversaire" || len(schedulerConfig.Options.Milestones) != 1 {
+		t.Errorf("Expected the templates to load but got %v", schedulerConfig.Options)
+	}
+
+	if _, ok := mustRepository(t, config).(MultiFriendsRepository); !ok {
+		t.Errorf("Expected several sources to be merged")
+	}
+}
+
+func mustRepository(t *testing.T, config Config) FriendsRepository {
+	repo, err := config.Repository()
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	return repo
+}
+
+func TestLoadConfigReportsEveryInvalidSetting(t *testing.T) {
+	path := writeConfig(t, `
+friends:
+  sources: []
+sender:
+  type: pigeon
+locale:
+  time_zone: Mars/Olympus
+time_zone:
+  send_window: "20:00"
+retry:
+  max_attempts: -1
+`)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatalf("Expected error to be raised but was not")
+	}
+
+	for _, want := range []string{"friends.sources", "sender.type", "locale.time_zone", "time_zone.send_window", "retry.max_attempts"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s but got '%v'", want, err.Error())
+		}
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+friends:
+  source: birthdays.txt
+`)
+
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "source") {
+		t.Errorf("Expected an unknown key error but got '%v'", err)
+	}
+}
+
+func TestLoadConfigRequiresSecretEnvironmentVariables(t *testing.T) {
+	path := writeConfig(t, `
+friends:
+  sources: [birthdays.txt]
+unsubscribe:
+  key: ${BIRTHDAY_UNSUBSCRIBE_KEY_MISSING}
+  base_url: https://example.com/unsubscribe
+`)
+
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "BIRTHDAY_UNSUBSCRIBE_KEY_MISSING is not set") {
+		t.Errorf("Expected a missing environment variable error but got '%v'", err)
+	}
+}
+
+func TestConfigSchedulerSendsWithConfiguredTemplates(t *testing.T) {
+	path := writeConfig(t, `
+friends:
+  sources: [birthdays.txt]
+templates:
+  birthday:
+    template: Joyeux anniversaire, {{.FirstName}} !
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	scheduler, err := config.Scheduler()
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	greetings, _ := scheduler.Plan(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+	if len(greetings) != 1 || greetings[0].Message() != "Joyeux anniversaire, John !" || greetings[0].Title() != "Happy Birthday" {
+		t.Errorf("Expected the configured birthday template but got %v", greetings)
+	}
+}
+
+func TestConfigReadsEncryptedFriends(t *testing.T) {
+	key := newEncryptionKey()
+	path := filepath.Join(t.TempDir(), "birthdays.enc")
+	repo, _ := NewEncryptedFriendsRepository(path, key)
+	repo.SaveFriends([]Friend{{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}})
+
+	t.Setenv("BIRTHDAY_FRIENDS_KEY", base64.StdEncoding.EncodeToString(key))
+	config := Config{Friends: FriendsConfig{Sources: []string{path}, KeyEnv: "BIRTHDAY_FRIENDS_KEY"}}
+
+	friends, err := mustRepository(t, config).GetFriends()
+	if err != nil || len(friends) != 1 || friends[0].Email != "john.doe@foobar.com" {
+		t.Errorf("Expected the encrypted friends to load but got %v, '%v'", friends, err)
+	}
+}
+
+func TestLoadConfigKeepsSecretsContainingDollars(t *testing.T) {
+	path := writeConfig(t, `
+friends:
+  sources: [birthdays.txt]
+sender:
+  type: smtp
+  from: greetings@example.com
+  smtp:
+    addr: smtp.example.com:587
+    password: pa$$word${HOME}}
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if config.Sender.SMTP.Password != "pa$$word${HOME}}" {
+		t.Errorf("Expected the password to be kept as is but got %q", config.Sender.SMTP.Password)
+	}
+}
+
+func TestLoadConfigReportsBrokenTemplates(t *testing.T) {
+	path := writeConfig(t, `
+friends:
+  sources: [birthdays.txt]
+templates:
+  birthday:
+    template: Happy birthday, {{.FirstName}
+  milestones:
+    - ages: [50]
+      title: "{{.Nickname}} is fifty"
+gift_reminders:
+  template: "{{if .Age}}turning {{.Age}}"
+`)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatalf("Expected error to be raised but was not")
+	}
+
+	for _, want := range []string{"templates.birthday.template", "templates.milestones[0].title", "gift_reminders.template"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s but got '%v'", want, err.Error())
+		}
+	}
+}
+
+func TestConfigVariantWeightsDefaultToOne(t *testing.T) {
+	path := writeConfig(t, `
+friends:
+  sources: [birthdays.txt]
+templates:
+  variants:
+    - template: Happy birthday, {{.FirstName}}!
+    - weight: 0
+      template: Never sent
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	variants := config.Templates.messageOptions().Variants
+	if len(variants) != 2 || variants[0].Weight != 1 || variants[1].Weight != 0 {
+		t.Errorf("Expected weights 1 and 0 but got %v", variants)
+	}
+}
@@ -0,0 +1,138 @@
+package birthday_greetings
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+var dashboardTemplate = template.Must(template.ParseFS(dashboardFiles, "dashboard/index.html.tmpl"))
+
+// Dashboard is a server-rendered overview of today's and upcoming birthdays
+// and of the scheduler's delivery history, with retries for failed sends.
+// Its links and redirects are relative, so it can be served under a path
+// prefix, and cross-origin POSTs are rejected.
+type Dashboard struct {
+	repo         FriendsRepository
+	scheduler    *Scheduler
+	upcomingDays int
+	now          func() time.Time
+	mux          *http.ServeMux
+	handler      http.Handler
+}
+
+type dashboardData struct {
+	Today     time.Time
+	Birthdays []Friend
+	Upcoming  []upcomingBirthday
+	Failed    []Receipt
+	History   []Receipt
+}
+
+func NewDashboard(repo FriendsRepository, scheduler *Scheduler, upcomingDays int) *Dashboard {
+	dashboard := &Dashboard{repo: repo, scheduler: scheduler, upcomingDays: upcomingDays, now: time.Now, mux: http.NewServeMux()}
+
+	static, _ := fs.Sub(dashboardFiles, "dashboard/static")
+	dashboard.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	dashboard.mux.HandleFunc("GET /{$}", dashboard.index)
+	dashboard.mux.HandleFunc("POST /retry/{id}", dashboard.retry)
+	dashboard.handler = http.NewCrossOriginProtection().Handler(dashboard.mux)
+
+	return dashboard
+}
+
+func (dashboard *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	dashboard.handler.ServeHTTP(w, r)
+}
+
+// index renders the overview, limited to the friends matching the tags query
+// parameter when it is set.
+func (dashboard *Dashboard) index(w http.ResponseWriter, r *http.Request) {
+	friends, err := dashboard.repo.GetFriends()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := parseRequestTags(r.URL.Query().Get("tags"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	friends = FilterFriends(friends, tags)
+
+	now := dashboard.now()
+	data := dashboardData{Today: now}
+
+	for _, friend := range friends {
+		if isBirthday, _ := friend.IsBirthdayAt(now); isBirthday {
+			data.Birthdays = append(data.Birthdays, friend)
+		}
+	}
+
+	data.Upcoming = upcomingBirthdays(friends, now, dashboard.upcomingDays)
+
+	if data.History, err = dashboard.scheduler.History().Query(HistoryQuery{}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if data.Failed, err = dashboard.scheduler.PendingFailures(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if tags.root != nil {
+		data.History = receiptsFor(data.History, friends)
+		data.Failed = receiptsFor(data.Failed, friends)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// receiptsFor keeps the receipts about one of friends.
+func receiptsFor(receipts []Receipt, friends []Friend) []Receipt {
+	ids := map[string]bool{}
+	for _, friend := range friends {
+		ids[friend.identity()] = true
+	}
+
+	kept := []Receipt{}
+	for _, receipt := range receipts {
+		for id := range ids {
+			if receipt.concerns(id) {
+				kept = append(kept, receipt)
+				break
+			}
+		}
+	}
+
+	return kept
+}
+
+// retry redirects back to the overview once the greeting is sent again. The
+// redirect is relative, since http.Redirect would resolve it against a path
+// a prefix was stripped from.
+func (dashboard *Dashboard) retry(w http.ResponseWriter, r *http.Request) {
+	receipt, err := dashboard.scheduler.Retry(r.PathValue("id"), dashboard.now())
+	switch {
+	case errors.Is(err, errDeliveryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil && receipt.Status == "":
+		http.Error(w, "retry refused: "+err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, "retry failed: "+err.Error(), http.StatusBadGateway)
+	default:
+		w.Header().Set("Location", "../")
+		w.WriteHeader(http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,113 @@
+package birthday_greetings
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestDashboard() (*Dashboard, *Scheduler) {
+	repository := TextFileFriendsRepository{path: "birthdays.txt"}
+	scheduler := NewScheduler(repository, SchedulerConfig{})
+	dashboard := NewDashboard(repository, scheduler, 7)
+	dashboard.now = func() time.Time { return time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC) }
+	return dashboard, scheduler
+}
+
+func TestDashboardShowsBirthdaysAndFailures(t *testing.T) {
+	dashboard, scheduler := newTestDashboard()
+	failed, _ := scheduler.send(Friend{}, BirthdayGreetings{recipient: "mary.ann@foobar.com", title: "Happy Birthday"}, time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC), "")
+	recorder := httptest.NewRecorder()
+
+	dashboard.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	body := recorder.Body.String()
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200 but got %d", recorder.Code)
+	}
+
+	for _, want := range []string{"<li>John Doe</li>", "mary.ann@foobar.com", "message is empty", `action="retry/` + failed.MessageID + `"`, `href="static/style.css"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected dashboard to contain '%s'", want)
+		}
+	}
+}
+
+func TestDashboardServesEmbeddedStylesheet(t *testing.T) {
+	dashboard, _ := newTestDashboard()
+	recorder := httptest.NewRecorder()
+
+	dashboard.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/static/style.css", nil))
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "font-family") {
+		t.Errorf("Expected the embedded stylesheet but got %d", recorder.Code)
+	}
+}
+
+func TestDashboardRetriesFailedDelivery(t *testing.T) {
+	dashboard, scheduler := newTestDashboard()
+	scheduler.config.Sender = failingSender{}
+	failed, _ := scheduler.send(Friend{}, BirthdayGreetings{recipient: "mary.ann@foobar.com", title: "Happy Birthday", message: "Happy birthday!"}, time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC), "")
+	scheduler.config.Sender = NoopSender{}
+	recorder := httptest.NewRecorder()
+
+	dashboard.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/retry/"+failed.MessageID, nil))
+
+	if recorder.Code != http.StatusSeeOther || recorder.Header().Get("Location") != "../" {
+		t.Errorf("Expected a relative redirect but got %d to %q", recorder.Code, recorder.Header().Get("Location"))
+	}
+
+	if receipts, _ := scheduler.History().Query(HistoryQuery{}); len(receipts) != 2 {
+		t.Errorf("Expected the retry to be recorded")
+	}
+}
+
+func TestDashboardRetryOfUnknownDelivery(t *testing.T) {
+	dashboard, _ := newTestDashboard()
+	recorder := httptest.NewRecorder()
+
+	dashboard.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/retry/unknown@birthday-greetings", nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 but got %d", recorder.Code)
+	}
+}
+
+func TestDashboardShowsRetryErrors(t *testing.T) {
+	dashboard, scheduler := newTestDashboard()
+	failed, _ := scheduler.send(Friend{}, BirthdayGreetings{recipient: "mary.ann@foobar.com"}, time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC), "")
+
+	recorder := httptest.NewRecorder()
+	dashboard.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/retry/"+failed.MessageID, nil))
+	if recorder.Code != http.StatusBadGateway || !strings.Contains(recorder.Body.String(), "retry failed: title is empty") {
+		t.Errorf("Expected the retry error but got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	dashboard.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/retry/"+failed.MessageID, nil))
+	if recorder.Code != http.StatusConflict || !strings.Contains(recorder.Body.String(), "already retried") {
+		t.Errorf("Expected the refusal but got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestDashboardRejectsCrossOriginRetries(t *testing.T) {
+	dashboard, scheduler := newTestDashboard()
+	scheduler.config.Sender = failingSender{}
+	failed, _ := scheduler.send(Friend{}, BirthdayGreetings{recipient: "mary.ann@foobar.com", title: "Happy Birthday", message: "Happy birthday!"}, time.Date(2024, 10, 7, 9, 0, 0, 0, time.UTC), "")
+	scheduler.config.Sender = NoopSender{}
+	request := httptest.NewRequest(http.MethodPost, "/retry/"+failed.MessageID, nil)
+	request.Header.Set("Sec-Fetch-Site", "cross-site")
+	recorder := httptest.NewRecorder()
+
+	dashboard.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 but got %d", recorder.Code)
+	}
+
+	if receipts, _ := scheduler.History().Query(HistoryQuery{}); len(receipts) != 1 {
+		t.Errorf("Expected no retry but got %v", receipts)
+	}
+}
@@ -0,0 +1,274 @@
+package birthday_greetings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"net"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+var errDeliveryNotFound = errors.New("delivery not found")
+
+var errOptedOut = errors.New("friend opted out")
+
+// send delivers greetings through the configured sender and records the
+// receipt in the history store. retryOf names the failed message being
+// retried, if any.
+func (scheduler *Scheduler) send(friend Friend, greetings BirthdayGreetings, at time.Time, retryOf string) (Receipt, error) {
+	sender, err := scheduler.routeFor(friend, greetings)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	friendID := receiptFriendID(friend, greetings)
+
+	started := time.Now()
+	receipt, err := greetings.SendWith(sender)
+	latency := time.Since(started)
+
+	switch {
+	case receipt.Failed():
+		scheduler.config.Metrics.observeSend(latency, err)
+		scheduler.logger().Error("sending greeting failed", "friend_id", friendID, "error_kind", errorKind(err))
+	case err != nil:
+		scheduler.config.Metrics.observeSend(latency, nil)
+		scheduler.logger().Error("archiving greeting failed", "friend_id", friendID, "error_kind", errorKind(err))
+	default:
+		scheduler.config.Metrics.observeSend(latency, nil)
+		scheduler.logger().Info("greeting sent", "friend_id", friendID, "latency", latency)
+	}
+
+	receipt.FriendID = friendID
+	receipt.SentAt = at
+	receipt.RetryOf = retryOf
+
+	if recordErr := scheduler.history().Record(receipt); recordErr != nil {
+		return receipt, errors.Join(err, recordErr)
+	}
+
+	return receipt, err
+}
+
+// receiptFriendID returns the ID of the friend the greetings' receipts
+// belong to. A digest is about several friends, so its receipts only mention
+// them.
+func receiptFriendID(friend Friend, greetings BirthdayGreetings) string {
+	if greetings.kind == DigestKind {
+		return ""
+	}
+
+	return friend.identity()
+}
+
+// History returns the store holding every delivery receipt.
+func (scheduler *Scheduler) History() HistoryStore {
+	return scheduler.history()
+}
+
+// Retry sends a failed delivery again, recording the new receipt as a retry
+// of the failed one.
+func (scheduler *Scheduler) Retry(messageID string, now time.Time) (Receipt, error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	return scheduler.retry(messageID, now)
+}
+
+func (scheduler *Scheduler) retry(messageID string, now time.Time) (Receipt, error) {
+	receipts, err := scheduler.history().Query(HistoryQuery{MessageID: messageID})
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	if len(receipts) == 0 {
+		return Receipt{}, errDeliveryNotFound
+	}
+
+	failed := receipts[0]
+	if !failed.Failed() {
+		return Receipt{}, errors.New("delivery did not fail")
+	}
+
+	if retried, err := scheduler.isRetried(failed); err != nil || retried {
+		return Receipt{}, errors.Join(err, errors.New("delivery was already retried"))
+	}
+
+	friend, err := scheduler.friendFor(failed)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	scheduler.config.Metrics.addRetried()
+	scheduler.logger().Info("retrying greeting", "friend_id", failed.FriendID, "message_id", messageID)
+
+	greetings := failed.greetings()
+	greetings.messageID = ""
+	return scheduler.send(friend, greetings, now, messageID)
+}
+
+// friendFor returns the friend a recorded delivery belongs to, so that it is
+// sent again through the friend's group. It fails with errOptedOut when the
+// friend opted out since. Digests belong to no friend.
+func (scheduler *Scheduler) friendFor(receipt Receipt) (Friend, error) {
+	if receipt.Kind == DigestKind {
+		return Friend{}, nil
+	}
+
+	friend, err := scheduler.friendByID(receipt.FriendID)
+	if err != nil {
+		return Friend{}, err
+	}
+
+	optedOut, err := scheduler.isOptedOut(friend)
+	if err != nil {
+		return Friend{}, err
+	}
+
+	if optedOut {
+		return Friend{}, errOptedOut
+	}
+
+	return friend, nil
+}
+
+// retryFailures retries the pending failures the retry policy allows: those
+// with fewer than MaxAttempts attempts so far whose last attempt is at least
+// Backoff old, skipping friends who opted out since. Its errors do not fail
+// the run, since the failed attempts are already recorded in the history.
+func (scheduler *Scheduler) retryFailures(now time.Time) error {
+	policy := scheduler.config.Retry
+	if policy.MaxAttempts <= 1 {
+		return nil
+	}
+
+	failures, err := scheduler.PendingFailures()
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, failure := range failures {
+		if now.Sub(failure.SentAt) < policy.Backoff {
+			continue
+		}
+
+		attempts, err := scheduler.attempts(failure)
+		if err != nil || attempts >= policy.MaxAttempts {
+			errs = append(errs, err)
+			continue
+		}
+
+		if _, err = scheduler.retry(failure.MessageID, now); !errors.Is(err, errOptedOut) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// attempts counts the deliveries in the retry chain ending with receipt.
+func (scheduler *Scheduler) attempts(receipt Receipt) (int, error) {
+	attempts := 1
+	for receipt.RetryOf != "" {
+		receipts, err := scheduler.history().Query(HistoryQuery{MessageID: receipt.RetryOf})
+		if err != nil || len(receipts) == 0 {
+			return attempts, err
+		}
+
+		receipt = receipts[0]
+		attempts++
+	}
+
+	return attempts, nil
+}
+
+// PendingFailures returns the failed receipts that have not been retried,
+// most recent first.
+func (scheduler *Scheduler) PendingFailures() ([]Receipt, error) {
+	failures, err := scheduler.history().Query(HistoryQuery{Status: Failed})
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Receipt{}
+	for _, failure := range failures {
+		retried, err := scheduler.isRetried(failure)
+		if err != nil {
+			return nil, err
+		}
+
+		if !retried {
+			pending = append(pending, failure)
+		}
+	}
+
+	return pending, nil
+}
+
+func (scheduler *Scheduler) isRetried(receipt Receipt) (bool, error) {
+	receipts, err := scheduler.history().Query(HistoryQuery{FriendID: receipt.FriendID})
+	if err != nil {
+		return false, err
+	}
+
+	for _, other := range receipts {
+		if other.RetryOf == receipt.MessageID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// greetingID derives the message ID from the friend, the event and the
+// celebration date, so that a greeting is only delivered once however many
+// times the scheduler runs that day.
+func greetingID(friend Friend, event Event, celebration time.Time) string {
+	sum := sha256.Sum256([]byte(friend.identity() + "\x00" + string(event.Type) + "\x00" + celebration.Format(birthDateLayout)))
+	return "greeting-" + hex.EncodeToString(sum[:12]) + "@birthday-greetings"
+}
+
+// isPlannedOrSent reports whether the message is in the history, held for
+// delivery or sent. Failed messages are left to retries.
+func (scheduler *Scheduler) isPlannedOrSent(messageID string) (bool, error) {
+	receipts, err := scheduler.history().Query(HistoryQuery{MessageID: messageID})
+	return len(receipts) > 0, err
+}
+
+// errorKind names the kind of a delivery error for the logs. The error text
+// is left to the receipt, since providers quote addresses in their answers.
+func errorKind(err error) string {
+	var protocolErr *textproto.Error
+	if errors.As(err, &protocolErr) {
+		return "smtp " + strconv.Itoa(protocolErr.Code)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return "filesystem"
+	}
+
+	return "other"
+}
+
+func (scheduler *Scheduler) sender() Sender {
+	if scheduler.config.Sender == nil {
+		return NoopSender{}
+	}
+
+	return scheduler.config.Sender
+}
+
+func (scheduler *Scheduler) history() HistoryStore {
+	return scheduler.config.History
+}
@@ -0,0 +1,126 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingSender struct{}
+
+func (failingSender) Send(greetings BirthdayGreetings) (Receipt, error) {
+	return Receipt{Channel: "test", ProviderResponse: "550 mailbox unavailable"}, errors.New("mailbox unavailable")
+}
+
+func TestSchedulerRecordsReceipts(t *testing.T) {
+	repository := TextFileFriendsRepository{path: "birthdays.txt"}
+	scheduler := NewScheduler(repository, SchedulerConfig{})
+	now := time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)
+
+	if err := scheduler.Run(now); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	receipts, err := scheduler.History().Query(HistoryQuery{FriendID: "c4f77c3c468f1411"})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(receipts) != 1 || receipts[0].Recipient != "john.doe@foobar.com" || receipts[0].Status != Sent || receipts[0].MessageID == "" {
+		t.Errorf("Expected one sent receipt for John but got %v", receipts)
+	}
+
+	if receipts[0].Channel != "noop" || !receipts[0].SentAt.Equal(now) {
+		t.Errorf("Expected a noop receipt at %v but got %v", now, receipts[0])
+	}
+}
+
+func TestSchedulerRecordsFailedReceiptAndRetriesIt(t *testing.T) {
+	repository := TextFileFriendsRepository{path: "birthdays.txt"}
+	scheduler := NewScheduler(repository, SchedulerConfig{Sender: failingSender{}})
+	now := time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)
+
+	if err := scheduler.Run(now); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	failures, _ := scheduler.PendingFailures()
+	if len(failures) != 1 || failures[0].ProviderResponse != "550 mailbox unavailable" {
+		t.Fatalf("Expected one failure with the provider response but got %v", failures)
+	}
+
+	scheduler.config.Sender = NoopSender{}
+	receipt, err := scheduler.Retry(failures[0].MessageID, now.Add(time.Hour))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if receipt.RetryOf != failures[0].MessageID || receipt.Message != failures[0].Message {
+		t.Errorf("Expected a retry of %s but got %v", failures[0].MessageID, receipt)
+	}
+
+	if pending, _ := scheduler.PendingFailures(); len(pending) != 0 {
+		t.Errorf("Expected no pending failures after the retry but got %d", len(pending))
+	}
+
+	if _, err := scheduler.Retry(failures[0].MessageID, now); err == nil {
+		t.Errorf("Expected a second retry to be refused")
+	}
+}
+
+func TestSchedulerRetryOfUnknownDelivery(t *testing.T) {
+	scheduler := NewScheduler(stubFriendsRepository{}, SchedulerConfig{})
+
+	_, err := scheduler.Retry("unknown@birthday-greetings", time.Now())
+
+	if err != errDeliveryNotFound {
+		t.Errorf("Expected error to be '%v' but got '%v'", errDeliveryNotFound, err)
+	}
+}
+
+func TestSchedulerRetriesFailuresWithinRetryPolicy(t *testing.T) {
+	repository := stubFriendsRepository{}
+	scheduler := NewScheduler(repository, SchedulerConfig{Sender: failingSender{}, Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Hour}})
+	now := time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)
+
+	greetings := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear John Doe!", recipient: "john.doe@foobar.com"}
+	scheduler.send(Friend{ID: "c4f77c3c468f1411"}, greetings, now, "")
+
+	for _, offset := range []time.Duration{30 * time.Minute, time.Hour, 90 * time.Minute, 2 * time.Hour, 3 * time.Hour} {
+		if err := scheduler.Run(now.Add(offset)); err != nil {
+			t.Errorf("Expected retry failures not to fail the run but got %v", err)
+		}
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{FriendID: "c4f77c3c468f1411"})
+	if len(receipts) != 3 {
+		t.Fatalf("Expected 3 attempts but got %d", len(receipts))
+	}
+
+	if !receipts[0].SentAt.Equal(now.Add(2*time.Hour)) || !receipts[1].SentAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected retries an hour apart but got %v and %v", receipts[1].SentAt, receipts[0].SentAt)
+	}
+}
+
+func TestSchedulerDoesNotRetryFriendsWhoOptedOut(t *testing.T) {
+	optOuts := NewMemoryOptOutStore()
+	scheduler := NewScheduler(stubFriendsRepository{}, SchedulerConfig{Sender: failingSender{}, OptOuts: optOuts, Retry: RetryPolicy{MaxAttempts: 3}})
+	now := time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)
+
+	greetings := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear John Doe!", recipient: "john.doe@foobar.com"}
+	failed, _ := scheduler.send(Friend{ID: "c4f77c3c468f1411"}, greetings, now, "")
+	optOuts.OptOut("c4f77c3c468f1411")
+	scheduler.config.Sender = NoopSender{}
+
+	if err := scheduler.Run(now.Add(time.Hour)); err != nil {
+		t.Errorf("Expected no error but got '%v'", err)
+	}
+
+	if _, err := scheduler.Retry(failed.MessageID, now.Add(time.Hour)); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	if receipts, _ := scheduler.History().Query(HistoryQuery{}); len(receipts) != 1 {
+		t.Errorf("Expected no retry after the opt-out but got %v", receipts)
+	}
+}
@@ -0,0 +1,259 @@
+package birthday_greetings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const DigestKind = "digest"
+
+const defaultDigestTitle = "Birthdays on {{.Date}}"
+
+const defaultDigestMessage = `Today's birthdays:
+{{range .Today}}- {{.FirstName}} {{.LastName}}
+{{end}}{{if .Upcoming}}
+Coming up:
+{{range .Upcoming}}- {{.Friend.FirstName}} {{.Friend.LastName}} on {{.Date}}
+{{end}}{{end}}`
+
+// DigestConfig limits the digest to the friends matching Tags, when set.
+type DigestConfig struct {
+	Recipients   []string
+	UpcomingDays int
+	Title        string
+	Template     string
+	Tags         TagExpression
+}
+
+type BirthdayDigest struct {
+	date       string
+	title      string
+	message    string
+	recipients []string
+	birthdays  []Friend
+	mentions   []string
+}
+
+type upcomingBirthday struct {
+	Friend Friend
+	Date   string
+}
+
+type digestData struct {
+	Date     string
+	Today    []Friend
+	Upcoming []upcomingBirthday
+}
+
+func BuildBirthdayDigest(friends []Friend, today time.Time, config DigestConfig) (BirthdayDigest, error) {
+	if len(config.Recipients) == 0 {
+		return BirthdayDigest{}, errors.New("digest recipients are empty")
+	}
+
+	if config.UpcomingDays < 0 {
+		return BirthdayDigest{}, errors.New("upcoming days cannot be negative")
+	}
+
+	data := digestData{Date: today.Format(birthDateLayout)}
+	friends = FilterFriends(friends, config.Tags)
+
+	for _, friend := range friends {
+		isBirthday, err := friend.IsBirthdayOn(today)
+		if err != nil {
+			return BirthdayDigest{}, err
+		}
+
+		if isBirthday {
+			data.Today = append(data.Today, friend)
+		}
+	}
+
+	if len(data.Today) == 0 {
+		return BirthdayDigest{}, nil
+	}
+
+	data.Upcoming = upcomingBirthdays(friends, today, config.UpcomingDays)
+
+	title, err := renderTemplate("digest title", orDefault(config.Title, defaultDigestTitle), data)
+	if err != nil {
+		return BirthdayDigest{}, err
+	}
+
+	message, err := renderTemplate("digest message", orDefault(config.Template, defaultDigestMessage), data)
+	if err != nil {
+		return BirthdayDigest{}, err
+	}
+
+	return BirthdayDigest{
+		date:       data.Date,
+		title:      title,
+		message:    message,
+		recipients: config.Recipients,
+		birthdays:  data.Today,
+		mentions:   data.mentions(),
+	}, nil
+}
+
+// mentions returns the IDs of the friends the digest names, so that their
+// receipts can be found when a friend asks for their data.
+func (data digestData) mentions() []string {
+	ids := []string{}
+	for _, friend := range data.Today {
+		ids = append(ids, friend.identity())
+	}
+
+	for _, upcoming := range data.Upcoming {
+		if !slices.Contains(ids, upcoming.Friend.identity()) {
+			ids = append(ids, upcoming.Friend.identity())
+		}
+	}
+
+	return ids
+}
+
+// upcomingBirthdays lists the birthdays in the days following today, in
+// date order.
+func upcomingBirthdays(friends []Friend, today time.Time, days int) []upcomingBirthday {
+	upcoming := []upcomingBirthday{}
+
+	for offset := 1; offset <= days; offset++ {
+		date := today.AddDate(0, 0, offset)
+		for _, friend := range friends {
+			if isBirthday, _ := friend.IsBirthdayAt(date); isBirthday {
+				upcoming = append(upcoming, upcomingBirthday{Friend: friend, Date: date.Format(birthDateLayout)})
+			}
+		}
+	}
+
+	return upcoming
+}
+
+func (digest BirthdayDigest) IsEmpty() bool {
+	return len(digest.birthdays) == 0
+}
+
+func (digest BirthdayDigest) Send() error {
+	_, err := digest.SendWith(NoopSender{})
+	return err
+}
+
+// SendWith sends the digest to every recipient through sender and returns a
+// receipt per recipient. It does nothing for an empty digest, so days
+// without birthdays produce no email.
+func (digest BirthdayDigest) SendWith(sender Sender) ([]Receipt, error) {
+	if digest.IsEmpty() {
+		return nil, nil
+	}
+
+	if err := digest.validate(); err != nil {
+		return nil, err
+	}
+
+	receipts := []Receipt{}
+	errs := []error{}
+	for _, greetings := range digest.greetings() {
+		receipt, err := greetings.SendWith(sender)
+		receipts = append(receipts, receipt)
+		errs = append(errs, err)
+	}
+
+	return receipts, errors.Join(errs...)
+}
+
+func (digest BirthdayDigest) validate() error {
+	if len(digest.recipients) == 0 {
+		return errors.New("recipients are empty")
+	}
+
+	if digest.title == "" {
+		return errors.New("title is empty")
+	}
+
+	if digest.message == "" {
+		return errors.New("message is empty")
+	}
+
+	return nil
+}
+
+// greetings returns the digest's message to each recipient. The message IDs
+// derive from the date and the recipient, so the history tells whether a
+// recipient already got the day's digest.
+func (digest BirthdayDigest) greetings() []BirthdayGreetings {
+	greetings := make([]BirthdayGreetings, len(digest.recipients))
+	for i, recipient := range digest.recipients {
+		sum := sha256.Sum256([]byte(digest.date + "\x00" + normalizeEmail(recipient)))
+		greetings[i] = BirthdayGreetings{
+			title:     digest.title,
+			message:   digest.message,
+			recipient: recipient,
+			messageID: "digest-" + hex.EncodeToString(sum[:12]) + "@birthday-greetings",
+			kind:      DigestKind,
+			mentions:  digest.mentions,
+		}
+	}
+
+	return greetings
+}
+
+// sendDigest sends the digest for the scheduler's date at now through the
+// scheduler's sender, once a day per recipient. Before the send window opens
+// it waits for a later run.
+func (scheduler *Scheduler) sendDigest(now time.Time) error {
+	if len(scheduler.config.Digest.Recipients) == 0 {
+		return nil
+	}
+
+	local, err := scheduler.localTime(Friend{}, now)
+	if err != nil || !scheduler.config.Window.Contains(local) {
+		return err
+	}
+
+	friends, err := scheduler.repo.GetFriends()
+	if err != nil {
+		return err
+	}
+
+	digest, err := BuildBirthdayDigest(FilterFriends(friends, scheduler.config.Tags), local, scheduler.config.Digest)
+	if err != nil || digest.IsEmpty() {
+		return err
+	}
+
+	errs := []error{}
+	for _, greetings := range digest.greetings() {
+		sent, err := scheduler.isPlannedOrSent(greetings.messageID)
+		if err == nil && !sent {
+			_, err = scheduler.send(Friend{}, greetings, now, "")
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func renderTemplate(name string, text string, data any) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+func orDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,140 @@
+package birthday_greetings
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildBirthdayDigestListsTodayAndUpcomingBirthdays(t *testing.T) {
+	friends := []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/11", Email: "mary.ann@foobar.com"},
+		{FirstName: "Jane", LastName: "Smith", BirthDate: "1990/05/15", Email: "jane.smith@example.com"},
+	}
+	today := time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC)
+	config := DigestConfig{Recipients: []string{"office@foobar.com"}, UpcomingDays: 7}
+
+	digest, err := BuildBirthdayDigest(friends, today, config)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	wantTitle := "Birthdays on 2024/10/08"
+	wantMessage := "Today's birthdays:\n- John Doe\n\nComing up:\n- Mary Ann on 2024/10/11\n"
+
+	if digest.title != wantTitle {
+		t.Errorf("Expected title '%s' but got '%s'", wantTitle, digest.title)
+	}
+
+	if digest.message != wantMessage {
+		t.Errorf("Expected message '%s' but got '%s'", wantMessage, digest.message)
+	}
+
+	if err := digest.Send(); err != nil {
+		t.Errorf("Expected no error but got '%v'", err.Error())
+	}
+}
+
+func TestBuildBirthdayDigestWithoutBirthdaysIsEmpty(t *testing.T) {
+	friends := []Friend{
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/11", Email: "mary.ann@foobar.com"},
+	}
+	today := time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC)
+	config := DigestConfig{Recipients: []string{"office@foobar.com"}, UpcomingDays: 7}
+
+	digest, err := BuildBirthdayDigest(friends, today, config)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !digest.IsEmpty() {
+		t.Errorf("Expected digest to be empty but got '%s'", digest.message)
+	}
+
+	if err := digest.Send(); err != nil {
+		t.Errorf("Expected no error but got '%v'", err.Error())
+	}
+}
+
+func TestBuildBirthdayDigestWithCustomTemplate(t *testing.T) {
+	friends := []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+	}
+	today := time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC)
+	config := DigestConfig{
+		Recipients: []string{"office@foobar.com"},
+		Title:      "Cake time",
+		Template:   "{{range .Today}}{{.FirstName}} {{end}}",
+	}
+
+	digest, err := BuildBirthdayDigest(friends, today, config)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if digest.title != "Cake time" || digest.message != "John " {
+		t.Errorf("Expected custom template to be used but got '%s' / '%s'", digest.title, digest.message)
+	}
+}
+
+func TestBuildBirthdayDigestWithoutRecipients(t *testing.T) {
+	_, err := BuildBirthdayDigest(nil, time.Now(), DigestConfig{})
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	if err.Error() != "digest recipients are empty" {
+		t.Errorf("Expected error message to be 'digest recipients are empty' but got '%v'", err.Error())
+	}
+}
+
+func TestBirthdayDigestSendsToEveryRecipient(t *testing.T) {
+	friends := []Friend{{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}}
+	config := DigestConfig{Recipients: []string{"office@foobar.com", "hr@foobar.com"}}
+	digest, _ := BuildBirthdayDigest(friends, time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC), config)
+
+	receipts, err := digest.SendWith(channelSender("smtp"))
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if len(receipts) != 2 || receipts[0].Recipient != "office@foobar.com" || receipts[1].Recipient != "hr@foobar.com" {
+		t.Fatalf("Expected a receipt per recipient but got %v", receipts)
+	}
+
+	if receipts[0].Channel != "smtp" || receipts[0].Kind != DigestKind || receipts[0].Title != "Birthdays on 2024/10/08" {
+		t.Errorf("Expected the digest to go through the sender but got %v", receipts[0])
+	}
+}
+
+func TestSchedulerSendsTheDigestOncePerDay(t *testing.T) {
+	scheduler := NewScheduler(stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+	}}, SchedulerConfig{
+		Window: SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour},
+		Digest: DigestConfig{Recipients: []string{"office@foobar.com"}},
+	})
+
+	for _, hour := range []int{6, 9, 10} {
+		if err := scheduler.Run(time.Date(2024, 10, 8, hour, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{})
+	digests := []Receipt{}
+	for _, receipt := range receipts {
+		if receipt.Recipient == "office@foobar.com" {
+			digests = append(digests, receipt)
+		}
+	}
+
+	if len(digests) != 1 || digests[0].Kind != DigestKind || digests[0].FriendID != "" {
+		t.Errorf("Expected a single digest but got %v", digests)
+	}
+
+	if !digests[0].SentAt.Equal(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the digest to wait for the send window but got %v", digests[0].SentAt)
+	}
+}
@@ -0,0 +1,270 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+type Canonicalization string
+
+const (
+	SimpleCanonicalization  Canonicalization = "simple"
+	RelaxedCanonicalization Canonicalization = "relaxed"
+)
+
+var defaultDKIMHeaders = []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"}
+
+type DKIMOptions struct {
+	Domain                 string
+	Selector               string
+	Headers                []string
+	HeaderCanonicalization Canonicalization
+	BodyCanonicalization   Canonicalization
+}
+
+// DKIMSigner adds a DKIM-Signature header (RFC 6376) to outgoing messages,
+// using RSA-SHA256 or Ed25519-SHA256 (RFC 8463) depending on the key.
+type DKIMSigner struct {
+	key       crypto.Signer
+	algorithm string
+	options   DKIMOptions
+	now       func() time.Time
+}
+
+// LoadDKIMKey reads an RSA or Ed25519 private key from a PEM file holding a
+// PKCS #1 or PKCS #8 key.
+func LoadDKIMKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found in DKIM key file")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported DKIM key type")
+	}
+
+	return signer, nil
+}
+
+func NewDKIMSigner(key crypto.Signer, options DKIMOptions) (*DKIMSigner, error) {
+	if options.Domain == "" || options.Selector == "" {
+		return nil, errors.New("DKIM domain and selector are required")
+	}
+
+	signer := &DKIMSigner{key: key, options: options, now: time.Now}
+
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		signer.algorithm = "rsa-sha256"
+	case ed25519.PrivateKey:
+		signer.algorithm = "ed25519-sha256"
+	default:
+		return nil, errors.New("unsupported DKIM key type")
+	}
+
+	if len(signer.options.Headers) == 0 {
+		signer.options.Headers = defaultDKIMHeaders
+	}
+
+	hasFrom := false
+	for _, header := range signer.options.Headers {
+		hasFrom = hasFrom || strings.EqualFold(header, "From")
+	}
+
+	if !hasFrom {
+		return nil, errors.New("DKIM signed headers must include From")
+	}
+
+	for _, canonicalization := range []*Canonicalization{&signer.options.HeaderCanonicalization, &signer.options.BodyCanonicalization} {
+		switch *canonicalization {
+		case "":
+			*canonicalization = RelaxedCanonicalization
+		case SimpleCanonicalization, RelaxedCanonicalization:
+		default:
+			return nil, fmt.Errorf("invalid DKIM canonicalization %q", *canonicalization)
+		}
+	}
+
+	return signer, nil
+}
+
+// Sign returns message, a CRLF separated RFC 5322 message, with a
+// DKIM-Signature header prepended.
+func (signer *DKIMSigner) Sign(message []byte) ([]byte, error) {
+	headers, body, err := splitMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyHash := sha256.Sum256(canonicalBody(body, signer.options.BodyCanonicalization))
+
+	names := make([]string, len(signer.options.Headers))
+	for i, name := range signer.options.Headers {
+		names[i] = strings.ToLower(name)
+	}
+
+	value := fmt.Sprintf("v=1; a=%s; c=%s/%s; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		signer.algorithm,
+		signer.options.HeaderCanonicalization,
+		signer.options.BodyCanonicalization,
+		signer.options.Domain,
+		signer.options.Selector,
+		signer.now().Unix(),
+		strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+
+	digest := dkimHeaderHash(headers, names, "DKIM-Signature: "+value+"\r\n", signer.options.HeaderCanonicalization)
+
+	var opts crypto.SignerOpts = crypto.SHA256
+	if signer.algorithm == "ed25519-sha256" {
+		opts = crypto.Hash(0)
+	}
+
+	signature, err := signer.key.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := "DKIM-Signature: " + value + base64.StdEncoding.EncodeToString(signature) + "\r\n"
+	return append([]byte(signed), message...), nil
+}
+
+// dkimHeaderHash hashes the signed headers, picking instances from the
+// bottom of the header block, followed by the signature header itself
+// without its trailing CRLF.
+func dkimHeaderHash(headers []string, names []string, signatureHeader string, canonicalization Canonicalization) []byte {
+	hash := sha256.New()
+	used := map[int]bool{}
+
+	for _, name := range names {
+		for i := len(headers) - 1; i >= 0; i-- {
+			if used[i] || !strings.EqualFold(headerName(headers[i]), name) {
+				continue
+			}
+
+			used[i] = true
+			hash.Write([]byte(canonicalHeader(headers[i], canonicalization)))
+			break
+		}
+	}
+
+	hash.Write([]byte(strings.TrimSuffix(canonicalHeader(signatureHeader, canonicalization), "\r\n")))
+	return hash.Sum(nil)
+}
+
+// splitMessage returns the raw header fields, folded lines included, and the
+// body of a CRLF separated message.
+func splitMessage(message []byte) ([]string, []byte, error) {
+	head, body, found := bytes.Cut(message, []byte("\r\n\r\n"))
+	if !found {
+		return nil, nil, errors.New("message has no header and body separator")
+	}
+
+	headers := []string{}
+	for _, line := range strings.SplitAfter(string(head)+"\r\n", "\r\n") {
+		if line == "" {
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1] += line
+			continue
+		}
+
+		headers = append(headers, line)
+	}
+
+	return headers, body, nil
+}
+
+func headerName(header string) string {
+	name, _, _ := strings.Cut(header, ":")
+	return strings.TrimSpace(name)
+}
+
+// canonicalHeader applies RFC 6376 section 3.4.2 relaxed canonicalization:
+// unfold, collapse runs of SP and HTAB and trim them around the value. Other
+// whitespace is kept as is.
+func canonicalHeader(header string, canonicalization Canonicalization) string {
+	if canonicalization == SimpleCanonicalization {
+		return header
+	}
+
+	name, value, _ := strings.Cut(header, ":")
+	value = strings.ReplaceAll(strings.TrimSuffix(value, "\r\n"), "\r\n", "")
+	value = strings.Trim(collapseWhitespace(value), " ")
+
+	return strings.ToLower(strings.TrimRight(name, " \t")) + ":" + value + "\r\n"
+}
+
+func canonicalBody(body []byte, canonicalization Canonicalization) []byte {
+	lines := strings.Split(string(body), "\r\n")
+
+	if canonicalization == RelaxedCanonicalization {
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(collapseWhitespace(line), " ")
+		}
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		if canonicalization == RelaxedCanonicalization {
+			return []byte{}
+		}
+
+		return []byte("\r\n")
+	}
+
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func collapseWhitespace(line string) string {
+	var builder strings.Builder
+	inWhitespace := false
+
+	for _, r := range line {
+		if r == ' ' || r == '\t' {
+			if !inWhitespace {
+				builder.WriteByte(' ')
+			}
+			inWhitespace = true
+			continue
+		}
+
+		inWhitespace = false
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
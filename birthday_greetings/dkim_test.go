@@ -0,0 +1,278 @@
+package birthday_greetings
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMessage = "From: greetings@example.com\r\n" +
+	"To: jane.smith@example.com\r\n" +
+	"Subject:  Happy   Birthday\r\n" +
+	"Date: Mon, 19 Oct 2026 09:00:00 +0000\r\n" +
+	"Message-ID: <1@birthday-greetings>\r\n" +
+	"\r\n" +
+	"Happy birthday,  dear Jane Doe!  \r\n" +
+	"\r\n"
+
+// verifyDKIM checks the DKIM-Signature header at the top of message against
+// public.
+func verifyDKIM(message []byte, public crypto.PublicKey) error {
+	headers, body, err := splitMessage(message)
+	if err != nil {
+		return err
+	}
+
+	if headerName(headers[0]) != "DKIM-Signature" {
+		return errors.New("missing DKIM-Signature header")
+	}
+
+	tags := map[string]string{}
+	_, value, _ := strings.Cut(headers[0], ":")
+	for _, tag := range strings.Split(value, ";") {
+		name, tagValue, _ := strings.Cut(strings.TrimSpace(tag), "=")
+		tags[name] = strings.Join(strings.Fields(tagValue), "")
+	}
+
+	headerCanonicalization, bodyCanonicalization, _ := strings.Cut(tags["c"], "/")
+
+	bodyHash := sha256.Sum256(canonicalBody(body, Canonicalization(bodyCanonicalization)))
+	if base64.StdEncoding.EncodeToString(bodyHash[:]) != tags["bh"] {
+		return errors.New("body hash mismatch")
+	}
+
+	unsigned := strings.TrimSuffix(headers[0], "\r\n")
+	unsigned = unsigned[:strings.LastIndex(unsigned, "b=")+2] + "\r\n"
+	digest := dkimHeaderHash(headers[1:], strings.Split(tags["h"], ":"), unsigned, Canonicalization(headerCanonicalization))
+
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return err
+	}
+
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, digest, signature) {
+			return errors.New("invalid Ed25519 signature")
+		}
+		return nil
+	}
+
+	return errors.New("unsupported key")
+}
+
+func TestDKIMSignerSignsWithRSA(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	signer, err := NewDKIMSigner(key, DKIMOptions{Domain: "example.com", Selector: "greetings"})
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	signed, err := signer.Sign([]byte(testMessage))
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !strings.Contains(string(signed), "a=rsa-sha256; c=relaxed/relaxed; d=example.com; s=greetings;") {
+		t.Errorf("Expected RSA signature tags but got %v", string(signed))
+	}
+
+	if err := verifyDKIM(signed, &key.PublicKey); err != nil {
+		t.Errorf("Expected a valid signature but got '%v'", err.Error())
+	}
+}
+
+func TestDKIMSignerSignsWithEd25519(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := NewDKIMSigner(private, DKIMOptions{Domain: "example.com", Selector: "greetings"})
+
+	signed, err := signer.Sign([]byte(testMessage))
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !strings.Contains(string(signed), "a=ed25519-sha256;") {
+		t.Errorf("Expected an Ed25519 signature but got %v", string(signed))
+	}
+
+	if err := verifyDKIM(signed, public); err != nil {
+		t.Errorf("Expected a valid signature but got '%v'", err.Error())
+	}
+}
+
+func TestDKIMSignerUsesConfiguredHeadersAndCanonicalization(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := NewDKIMSigner(private, DKIMOptions{
+		Domain:                 "example.com",
+		Selector:               "greetings",
+		Headers:                []string{"From", "Subject"},
+		HeaderCanonicalization: SimpleCanonicalization,
+		BodyCanonicalization:   SimpleCanonicalization,
+	})
+
+	signed, _ := signer.Sign([]byte(testMessage))
+
+	if !strings.Contains(string(signed), "c=simple/simple;") || !strings.Contains(string(signed), "h=from:subject;") {
+		t.Errorf("Expected the configured tags but got %v", string(signed))
+	}
+
+	if err := verifyDKIM(signed, public); err != nil {
+		t.Errorf("Expected a valid signature but got '%v'", err.Error())
+	}
+
+	unsignedChange := strings.Replace(string(signed), "To: jane.smith@example.com", "To: john.doe@example.com", 1)
+	if err := verifyDKIM([]byte(unsignedChange), public); err != nil {
+		t.Errorf("Expected changes to unsigned headers to keep the signature valid but got '%v'", err.Error())
+	}
+
+	signedChange := strings.Replace(string(signed), "Subject:  Happy", "Subject: Happy", 1)
+	if err := verifyDKIM([]byte(signedChange), public); err == nil {
+		t.Errorf("Expected simple canonicalization to reject whitespace changes but did not")
+	}
+}
+
+func TestDKIMSignatureDetectsTampering(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	signer, _ := NewDKIMSigner(key, DKIMOptions{Domain: "example.com", Selector: "greetings"})
+	signed, _ := signer.Sign([]byte(testMessage))
+
+	relaxedChange := strings.Replace(string(signed), "Subject:  Happy   Birthday", "subject: Happy Birthday", 1)
+	if err := verifyDKIM([]byte(relaxedChange), &key.PublicKey); err != nil {
+		t.Errorf("Expected relaxed canonicalization to accept whitespace changes but got '%v'", err.Error())
+	}
+
+	tampered := strings.Replace(string(signed), "dear Jane", "dear John", 1)
+	if err := verifyDKIM([]byte(tampered), &key.PublicKey); err == nil {
+		t.Errorf("Expected a tampered body to fail verification but did not")
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if err := verifyDKIM(signed, &otherKey.PublicKey); err == nil {
+		t.Errorf("Expected another key to fail verification but did not")
+	}
+}
+
+func TestNewDKIMSignerRejectsInvalidOptions(t *testing.T) {
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []DKIMOptions{
+		{Selector: "greetings"},
+		{Domain: "example.com", Selector: "greetings", Headers: []string{"Subject"}},
+		{Domain: "example.com", Selector: "greetings", BodyCanonicalization: "strict"},
+	}
+
+	for _, options := range tests {
+		if _, err := NewDKIMSigner(private, options); err == nil {
+			t.Errorf("Expected error to be raised for %v but was not", options)
+		}
+	}
+}
+
+func TestLoadDKIMKeyReadsPEMFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaPath := filepath.Join(dir, "rsa.pem")
+	os.WriteFile(rsaPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), 0o600)
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edBytes, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	edPath := filepath.Join(dir, "ed25519.pem")
+	os.WriteFile(edPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edBytes}), 0o600)
+
+	loaded, err := LoadDKIMKey(rsaPath)
+	if err != nil || !rsaKey.Equal(loaded) {
+		t.Errorf("Expected the RSA key to load but got '%v'", err)
+	}
+
+	loaded, err = LoadDKIMKey(edPath)
+	if err != nil || !edKey.Equal(loaded) {
+		t.Errorf("Expected the Ed25519 key to load but got '%v'", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "empty.pem"), []byte("not a key"), 0o600)
+	if _, err := LoadDKIMKey(filepath.Join(dir, "empty.pem")); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+// rfc8463Message is the example message signed with Ed25519-SHA256 in
+// RFC 8463, appendix A, signed by an implementation independent of this one.
+const rfc8463Message = "DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;\r\n" +
+	" d=football.example.com; i=@football.example.com;\r\n" +
+	" q=dns/txt; s=brisbane; t=1528637909; h=from : to :\r\n" +
+	" subject : date : message-id : from : subject : date;\r\n" +
+	" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+	" b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus\r\n" +
+	" Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n" +
+	"From: Joe SixPack <joe@football.example.com>\r\n" +
+	"To: Suzie Q <suzie@shopping.example.net>\r\n" +
+	"Subject: Is dinner ready?\r\n" +
+	"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\r\n" +
+	"Message-ID: <20030712040037.46341.5F8J@football.example.com>\r\n" +
+	"\r\n" +
+	"Hi.\r\n" +
+	"\r\n" +
+	"We lost the game.  Are you hungry yet?\r\n" +
+	"\r\n" +
+	"Joe.\r\n"
+
+const rfc8463PrivateKey = "nWGxne/9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A="
+
+const rfc8463PublicKey = "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+
+func TestDKIMCanonicalizationMatchesRFC8463Example(t *testing.T) {
+	public, _ := base64.StdEncoding.DecodeString(rfc8463PublicKey)
+
+	if err := verifyDKIM([]byte(rfc8463Message), ed25519.PublicKey(public)); err != nil {
+		t.Errorf("Expected the RFC 8463 signature to verify but got '%v'", err)
+	}
+}
+
+func TestDKIMSignerMatchesRFC8463Example(t *testing.T) {
+	seed, _ := base64.StdEncoding.DecodeString(rfc8463PrivateKey)
+	key := ed25519.NewKeyFromSeed(seed)
+	if public, _ := base64.StdEncoding.DecodeString(rfc8463PublicKey); !key.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(public)) {
+		t.Fatalf("Expected the RFC 8463 key pair to match")
+	}
+
+	signer, _ := NewDKIMSigner(key, DKIMOptions{
+		Domain:   "football.example.com",
+		Selector: "brisbane",
+		Headers:  []string{"From", "To", "Subject", "Date", "Message-ID", "From", "Subject", "Date"},
+	})
+	signer.now = func() time.Time { return time.Unix(1528637909, 0) }
+
+	_, unsigned, _ := strings.Cut(rfc8463Message, "Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n")
+	signed, err := signer.Sign([]byte(unsigned))
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !strings.Contains(string(signed), "bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;") {
+		t.Errorf("Expected the RFC 8463 body hash but got %q", signed)
+	}
+}
+
+func TestRelaxedHeaderCanonicalizationOnlyCollapsesWSP(t *testing.T) {
+	header := "Subject \t:  Happy \t Birthday\u00a0\u00a0Jane\r\n \tDoe  \r\n"
+
+	got := canonicalHeader(header, RelaxedCanonicalization)
+
+	if want := "subject:Happy Birthday\u00a0\u00a0Jane Doe\r\n"; got != want {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+}
@@ -0,0 +1,221 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// encryptedFriendsHeader starts every encrypted friends file and is
+// authenticated along with the content.
+const encryptedFriendsHeader = "birthday-greetings friends v1\n"
+
+const encryptionKeySize = 32
+
+// EncryptedFriendsRepository stores the friends file encrypted with
+// AES-256-GCM. The plaintext uses the same CSV format as
+// TextFileFriendsRepository.
+type EncryptedFriendsRepository struct {
+	mu   sync.Mutex
+	path string
+	key  []byte
+}
+
+func NewEncryptedFriendsRepository(path string, key []byte) (*EncryptedFriendsRepository, error) {
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", encryptionKeySize)
+	}
+
+	return &EncryptedFriendsRepository{path: path, key: key}, nil
+}
+
+// LoadEncryptionKey reads a base64 encoded key from a key file.
+func LoadEncryptionKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeEncryptionKey(string(data))
+}
+
+// EncryptionKeyFromEnv reads a base64 encoded key from an environment
+// variable.
+func EncryptionKeyFromEnv(name string) ([]byte, error) {
+	value, found := os.LookupEnv(name)
+	if !found {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return decodeEncryptionKey(value)
+}
+
+// GenerateEncryptionKey returns a new random key, to rotate to.
+func GenerateEncryptionKey() ([]byte, error) {
+	key := make([]byte, encryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// SaveEncryptionKey atomically replaces the key file at path with key,
+// base64 encoded as LoadEncryptionKey reads it.
+func SaveEncryptionKey(path string, key []byte) error {
+	if len(key) != encryptionKeySize {
+		return fmt.Errorf("encryption key must be %d bytes", encryptionKeySize)
+	}
+
+	return writeFileAtomically(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"))
+}
+
+func decodeEncryptionKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.New("encryption key is not valid base64")
+	}
+
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", encryptionKeySize)
+	}
+
+	return key, nil
+}
+
+func (repo *EncryptedFriendsRepository) Name() string {
+	return repo.path
+}
+
+func (repo *EncryptedFriendsRepository) GetFriends() ([]Friend, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	data, err := os.ReadFile(repo.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.parse(data)
+}
+
+// parseFriendsFile decrypts data, the content of the friends file, and reads
+// the friends in it.
+func (repo *EncryptedFriendsRepository) parseFriendsFile(data []byte) ([]Friend, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.parse(data)
+}
+
+func (repo *EncryptedFriendsRepository) parse(data []byte) ([]Friend, error) {
+	plaintext, err := repo.open(repo.key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseFriends(bytes.NewReader(plaintext), TextFileOptions{})
+}
+
+func (repo *EncryptedFriendsRepository) SaveFriends(friends []Friend) error {
+	plaintext, err := formatFriends(friends, CSVFormat{})
+	if err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	return repo.write(repo.key, plaintext)
+}
+
+// RotateKey re-encrypts the file with newKey, which the repository uses from
+// then on. Store newKey before rotating, for instance with SaveEncryptionKey
+// to a new key file, so that a crash cannot leave the file encrypted with a
+// key kept nowhere, and keep the old key until RotateKey returns: the file is
+// replaced atomically, so it stays readable with the old key if rotation
+// fails.
+func (repo *EncryptedFriendsRepository) RotateKey(newKey []byte) error {
+	if len(newKey) != encryptionKeySize {
+		return fmt.Errorf("encryption key must be %d bytes", encryptionKeySize)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	plaintext, err := repo.read(repo.key)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.write(newKey, plaintext); err != nil {
+		return err
+	}
+
+	repo.key = newKey
+	return nil
+}
+
+func (repo *EncryptedFriendsRepository) read(key []byte) ([]byte, error) {
+	data, err := os.ReadFile(repo.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.open(key, data)
+}
+
+func (repo *EncryptedFriendsRepository) open(key []byte, data []byte) ([]byte, error) {
+	sealed, found := bytes.CutPrefix(data, []byte(encryptedFriendsHeader))
+	if !found {
+		return nil, errors.New("friends file is not encrypted")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted friends file is truncated")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(encryptedFriendsHeader))
+	if err != nil {
+		return nil, errors.New("cannot decrypt friends file, wrong key or corrupted file")
+	}
+
+	return plaintext, nil
+}
+
+func (repo *EncryptedFriendsRepository) write(key []byte, plaintext []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data := append([]byte(encryptedFriendsHeader), nonce...)
+	data = aead.Seal(data, nonce, plaintext, []byte(encryptedFriendsHeader))
+
+	return writeFileAtomically(repo.path, data)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,154 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newEncryptionKey() []byte {
+	key := make([]byte, encryptionKeySize)
+	rand.Read(key)
+	return key
+}
+
+func TestEncryptedFriendsRepositoryRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "birthdays.enc")
+	repo, err := NewEncryptedFriendsRepository(path, newEncryptionKey())
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	friends, _ := TextFileFriendsRepository{path: "birthdays.txt"}.GetFriends()
+	if err := repo.SaveFriends(friends); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("john.doe@foobar.com")) || bytes.Contains(data, []byte("Doe")) {
+		t.Errorf("Expected the friends file to be encrypted but got %q", data)
+	}
+
+	got, err := repo.GetFriends()
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !reflect.DeepEqual(got, friends) {
+		t.Errorf("Expected %v but got %v", friends, got)
+	}
+}
+
+func TestEncryptedFriendsRepositoryRejectsWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "birthdays.enc")
+	repo, _ := NewEncryptedFriendsRepository(path, newEncryptionKey())
+	repo.SaveFriends([]Friend{{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}})
+
+	other, _ := NewEncryptedFriendsRepository(path, newEncryptionKey())
+	if _, err := other.GetFriends(); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	plain, _ := NewEncryptedFriendsRepository("birthdays.txt", newEncryptionKey())
+	if _, err := plain.GetFriends(); err == nil || err.Error() != "friends file is not encrypted" {
+		t.Errorf("Expected a not encrypted error but got '%v'", err)
+	}
+}
+
+func TestEncryptedFriendsRepositoryRotatesKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "birthdays.enc")
+	keyPath := filepath.Join(t.TempDir(), "friends.key")
+	oldKey := newEncryptionKey()
+	repo, _ := NewEncryptedFriendsRepository(path, oldKey)
+	friends := []Friend{{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}}
+	repo.SaveFriends(friends)
+
+	newKey, err := GenerateEncryptionKey()
+	if err != nil || bytes.Equal(newKey, oldKey) {
+		t.Fatalf("Expected a new key but got '%v'", err)
+	}
+
+	if err := SaveEncryptionKey(keyPath, newKey); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if err := repo.RotateKey(newKey); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if got, err := repo.GetFriends(); err != nil || len(got) != 1 || got[0].Email != "john.doe@foobar.com" {
+		t.Errorf("Expected the friends to stay readable but got %v, '%v'", got, err)
+	}
+
+	withOldKey, _ := NewEncryptedFriendsRepository(path, oldKey)
+	if _, err := withOldKey.GetFriends(); err == nil {
+		t.Errorf("Expected the old key to be rejected after rotation")
+	}
+
+	storedKey, _ := LoadEncryptionKey(keyPath)
+	withNewKey, _ := NewEncryptedFriendsRepository(path, storedKey)
+	if _, err := withNewKey.GetFriends(); err != nil {
+		t.Errorf("Expected the new key to work but got '%v'", err.Error())
+	}
+}
+
+func TestEncryptedFriendsRepositoryStaysReadableWhenRotationStops(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "birthdays.enc")
+	keyPath := filepath.Join(dir, "friends.key")
+	oldKey := newEncryptionKey()
+	repo, _ := NewEncryptedFriendsRepository(path, oldKey)
+	repo.SaveFriends([]Friend{{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}})
+
+	// The new key is stored, then the rotation stops before the file is
+	// replaced.
+	SaveEncryptionKey(keyPath, newEncryptionKey())
+	if err := repo.RotateKey(make([]byte, 16)); err == nil {
+		t.Errorf("Expected a short key to be rejected")
+	}
+
+	withOldKey, _ := NewEncryptedFriendsRepository(path, oldKey)
+	if _, err := withOldKey.GetFriends(); err != nil {
+		t.Errorf("Expected the old key to still read the file but got '%v'", err.Error())
+	}
+
+	storedKey, _ := LoadEncryptionKey(keyPath)
+	if err := withOldKey.RotateKey(storedKey); err != nil {
+		t.Fatalf("Expected the rotation to resume with the stored key but got '%v'", err.Error())
+	}
+
+	withStoredKey, _ := NewEncryptedFriendsRepository(path, storedKey)
+	if _, err := withStoredKey.GetFriends(); err != nil {
+		t.Errorf("Expected the stored key to read the file but got '%v'", err.Error())
+	}
+}
+
+func TestLoadEncryptionKey(t *testing.T) {
+	key := newEncryptionKey()
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	path := filepath.Join(t.TempDir(), "friends.key")
+	os.WriteFile(path, []byte(encoded+"\n"), 0o600)
+
+	if loaded, err := LoadEncryptionKey(path); err != nil || !bytes.Equal(loaded, key) {
+		t.Errorf("Expected the key file to load but got '%v'", err)
+	}
+
+	t.Setenv("BIRTHDAY_FRIENDS_KEY", encoded)
+	if loaded, err := EncryptionKeyFromEnv("BIRTHDAY_FRIENDS_KEY"); err != nil || !bytes.Equal(loaded, key) {
+		t.Errorf("Expected the environment key to load but got '%v'", err)
+	}
+
+	t.Setenv("BIRTHDAY_FRIENDS_KEY", base64.StdEncoding.EncodeToString([]byte("too short")))
+	if _, err := EncryptionKeyFromEnv("BIRTHDAY_FRIENDS_KEY"); err == nil {
+		t.Errorf("Expected a short key to be rejected")
+	}
+
+	if _, err := EncryptionKeyFromEnv("BIRTHDAY_FRIENDS_KEY_MISSING"); err == nil {
+		t.Errorf("Expected a missing variable to be rejected")
+	}
+}
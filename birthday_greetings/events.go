@@ -0,0 +1,182 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	Birthday           EventType = "birthday"
+	WorkAnniversary    EventType = "work_anniversary"
+	WeddingAnniversary EventType = "wedding_anniversary"
+	NameDay            EventType = "name_day"
+)
+
+type Recurrence int
+
+const (
+	Yearly Recurrence = iota
+	Once
+)
+
+// Event is a personal date worth celebrating. Dates use the same formats as
+// birth dates, so yearly events may omit the year.
+type Event struct {
+	Type       EventType  `json:"type"`
+	Date       string     `json:"date"`
+	Recurrence Recurrence `json:"recurrence"`
+}
+
+type EventTemplate struct {
+	Title    string
+	Template string
+}
+
+var defaultEventTemplates = map[EventType]EventTemplate{
+	WorkAnniversary: {
+		Title:    "Happy Work Anniversary",
+		Template: "Happy work anniversary, dear {{.FirstName}} {{.LastName}}!{{if .Age}} {{.Age}} years already!{{end}}",
+	},
+	WeddingAnniversary: {
+		Title:    "Happy Wedding Anniversary",
+		Template: "Happy wedding anniversary, dear {{.FirstName}} {{.LastName}}!",
+	},
+	NameDay: {
+		Title:    "Happy Name Day",
+		Template: "Happy name day, dear {{.FirstName}} {{.LastName}}!",
+	},
+}
+
+// eventColumns maps the optional friends file columns to the events they hold.
+// Each event column may be followed by a recurrence column, such as
+// "wedding_anniversary_recurrence", holding "yearly" or "once". Events
+// without one recur yearly.
+var eventColumns = map[string]EventType{
+	"work_anniversary":    WorkAnniversary,
+	"wedding_anniversary": WeddingAnniversary,
+	"name_day":            NameDay,
+}
+
+const recurrenceColumnSuffix = "_recurrence"
+
+// recurrenceColumn returns the event type whose recurrence column is named
+// column.
+func recurrenceColumn(column string) (EventType, bool) {
+	name, found := strings.CutSuffix(column, recurrenceColumnSuffix)
+	if !found {
+		return "", false
+	}
+
+	eventType, found := eventColumns[name]
+	return eventType, found
+}
+
+func parseRecurrence(value string) (Recurrence, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "yearly":
+		return Yearly, nil
+	case "once":
+		return Once, nil
+	}
+
+	return Yearly, fmt.Errorf("invalid recurrence %q", value)
+}
+
+func (recurrence Recurrence) String() string {
+	switch recurrence {
+	case Yearly:
+		return "yearly"
+	case Once:
+		return "once"
+	}
+
+	return fmt.Sprintf("Recurrence(%d)", int(recurrence))
+}
+
+// AllEvents returns the friend's birthday followed by their other events.
+func (friend Friend) AllEvents() []Event {
+	events := []Event{{Type: Birthday, Date: friend.BirthDate, Recurrence: Yearly}}
+	for _, event := range friend.Events {
+		if event.Type != Birthday {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func (event Event) OccursOn(date time.Time) (bool, error) {
+	eventDate, err := parseBirthDate(event.Date)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s date", event.Type)
+	}
+
+	switch event.Recurrence {
+	case Yearly:
+		return sameAnniversary(eventDate.month, eventDate.day, date), nil
+	case Once:
+		if !eventDate.hasYear() {
+			return false, fmt.Errorf("%s happening once needs a year", event.Type)
+		}
+
+		return date.Year() == eventDate.year && date.Month() == eventDate.month && date.Day() == eventDate.day, nil
+	default:
+		return false, errors.New("invalid recurrence")
+	}
+}
+
+// BuildEventMessage builds the greeting for event on date using the event
+// type's template. Birthdays are built like BuildBirthdayMessageOn. The Age
+// available to templates counts the years since the event.
+func (friend Friend) BuildEventMessage(event Event, date time.Time, options MessageOptions) (BirthdayGreetings, error) {
+	return friend.buildEventMessage(event, date, date, options)
+}
+
+// buildEventMessage builds the greeting for event on celebration that is
+// delivered on sendDay. Like birthday greetings, greetings delivered on
+// another day mention the real date.
+func (friend Friend) buildEventMessage(event Event, celebration time.Time, sendDay time.Time, options MessageOptions) (BirthdayGreetings, error) {
+	if event.Type == Birthday {
+		return friend.buildBirthdayMessage(celebration, sendDay, false, options)
+	}
+
+	if err := friend.validate(); err != nil {
+		return BirthdayGreetings{}, err
+	}
+
+	eventDate, err := parseBirthDate(event.Date)
+	if err != nil {
+		return BirthdayGreetings{}, fmt.Errorf("invalid %s date", event.Type)
+	}
+
+	eventTemplate, found := options.EventTemplates[event.Type]
+	if !found {
+		eventTemplate, found = defaultEventTemplates[event.Type]
+	}
+
+	if !found {
+		return BirthdayGreetings{}, fmt.Errorf("no template for %s", event.Type)
+	}
+
+	data := greetingData{FirstName: friend.FirstName, LastName: friend.LastName, Age: eventDate.ageOn(celebration), Date: celebration.Format("Monday, January 2")}
+
+	title, err := renderTemplate(string(event.Type)+" title", eventTemplate.Title, data)
+	if err != nil {
+		return BirthdayGreetings{}, err
+	}
+
+	message, err := renderTemplate(string(event.Type)+" message", eventTemplate.Template, data)
+	if err != nil {
+		return BirthdayGreetings{}, err
+	}
+
+	if !sameDay(celebration, sendDay) {
+		message += fmt.Sprintf(" Your %s is on %s.", strings.ReplaceAll(string(event.Type), "_", " "), data.Date)
+	}
+
+	return BirthdayGreetings{title: title, message: message}, nil
+}
@@ -0,0 +1,151 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetFriendsFromTextFileWithEventColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "last_name, first_name, birth_date, email, work_anniversary, name_day\nDoe, John, 1982/10/08, john.doe@foobar.com, 2015/03/01, 12/06\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repository := TextFileFriendsRepository{path: path}
+	want := []Event{
+		{Type: WorkAnniversary, Date: "2015/03/01", Recurrence: Yearly},
+		{Type: NameDay, Date: "12/06", Recurrence: Yearly},
+	}
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(friends[0].Events, want) {
+		t.Errorf("Expected %v but got %v", want, friends[0].Events)
+	}
+}
+
+func TestGetFriendsFromTextFileWithRecurrenceColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "last_name, first_name, birth_date, email, wedding_anniversary, wedding_anniversary_recurrence, name_day\nDoe, John, 1982/10/08, john.doe@foobar.com, 2025/06/14, once, 12/06\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repository := TextFileFriendsRepository{path: path}
+	want := []Event{
+		{Type: WeddingAnniversary, Date: "2025/06/14", Recurrence: Once},
+		{Type: NameDay, Date: "12/06", Recurrence: Yearly},
+	}
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(friends[0].Events, want) {
+		t.Errorf("Expected %v but got %v", want, friends[0].Events)
+	}
+
+	if err := repository.SaveFriends(friends); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if again, _ := repository.GetFriends(); !reflect.DeepEqual(again, friends) {
+		t.Errorf("Expected %v but got %v", friends, again)
+	}
+}
+
+func TestGetFriendsFromTextFileWithInvalidRecurrence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "last_name, first_name, birth_date, email, name_day, name_day_recurrence\nDoe, John, 1982/10/08, john.doe@foobar.com, 12/06, monthly\n"
+	os.WriteFile(path, []byte(content), 0o600)
+
+	_, err := TextFileFriendsRepository{path: path}.GetFriends()
+	if err == nil || err.Error() != `name_day_recurrence: invalid recurrence "monthly"` {
+		t.Errorf("Expected an invalid recurrence error but got '%v'", err)
+	}
+}
+
+func TestBuildEventMessageBeforeTheEventHasNoAge(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	event := Event{Type: WorkAnniversary, Date: "2026/03/01"}
+
+	greetings, err := friend.BuildEventMessage(event, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), MessageOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if greetings.message != "Happy work anniversary, dear John Doe!" {
+		t.Errorf("Expected no age in the message but got %q", greetings.message)
+	}
+}
+
+func TestAllEventsStartsWithBirthday(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com", Events: []Event{{Type: NameDay, Date: "12/06"}}}
+
+	events := friend.AllEvents()
+
+	if len(events) != 2 || events[0].Type != Birthday || events[1].Type != NameDay {
+		t.Errorf("Expected birthday then name day but got %v", events)
+	}
+}
+
+func TestEventHappeningOnceOccursOnlyOnItsDate(t *testing.T) {
+	event := Event{Type: WeddingAnniversary, Date: "2024/06/01", Recurrence: Once}
+
+	thisYear, _ := event.OccursOn(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	nextYear, _ := event.OccursOn(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	if !thisYear || nextYear {
+		t.Errorf("Expected event to occur in 2024 only")
+	}
+}
+
+func TestBuildEventMessageForWorkAnniversary(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	event := Event{Type: WorkAnniversary, Date: "2015/03/01"}
+	want := BirthdayGreetings{title: "Happy Work Anniversary", message: "Happy work anniversary, dear John Doe! 9 years already!"}
+
+	got, err := friend.BuildEventMessage(event, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), MessageOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected '%v' but got '%v'", want, got)
+	}
+}
+
+func TestBuildEventMessageWithCustomTemplate(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	event := Event{Type: NameDay, Date: "12/06"}
+	options := MessageOptions{EventTemplates: map[EventType]EventTemplate{
+		NameDay: {Title: "Bonne fête", Template: "Bonne fête, {{.FirstName}} !"},
+	}}
+	want := BirthdayGreetings{title: "Bonne fête", message: "Bonne fête, John !"}
+
+	got, err := friend.BuildEventMessage(event, time.Date(2024, 12, 6, 0, 0, 0, 0, time.UTC), options)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected '%v' but got '%v'", want, got)
+	}
+}
+
+func TestBuildEventMessageWithoutTemplate(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	event := Event{Type: "graduation", Date: "2004/06/30"}
+
+	_, err := friend.BuildEventMessage(event, time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC), MessageOptions{})
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
@@ -0,0 +1,124 @@
+package birthday_greetings
+
+import (
+	"fmt"
+	"strings"
+)
+
+var defaultFriendColumns = []string{"last_name", "first_name", "birth_date", "email"}
+
+// friendColumns returns the column names of a friends file and the records
+// that follow them. Files without a header row use the default four columns;
+// a header row naming the columns allows optional extra columns.
+func friendColumns(rows [][]string) ([]string, [][]string, error) {
+	if len(rows) == 0 || !isFriendColumn(strings.ToLower(strings.TrimSpace(rows[0][0]))) {
+		if len(rows) > 0 && len(rows[0]) != len(defaultFriendColumns) {
+			return nil, nil, fmt.Errorf("expected %d fields but got %d", len(defaultFriendColumns), len(rows[0]))
+		}
+
+		return defaultFriendColumns, rows, nil
+	}
+
+	columns := make([]string, len(rows[0]))
+	seen := map[string]bool{}
+	for i, name := range rows[0] {
+		columns[i] = strings.ToLower(strings.TrimSpace(name))
+		if !isFriendColumn(columns[i]) {
+			return nil, nil, fmt.Errorf("unknown column %q", columns[i])
+		}
+
+		if seen[columns[i]] {
+			return nil, nil, fmt.Errorf("duplicate column %q", columns[i])
+		}
+		seen[columns[i]] = true
+	}
+
+	for _, required := range defaultFriendColumns {
+		if !seen[required] {
+			return nil, nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	return columns, rows[1:], nil
+}
+
+func isFriendColumn(name string) bool {
+	switch name {
+	case "id", "last_name", "first_name", "birth_date", "email", "time_zone", "tags":
+		return true
+	}
+
+	_, isEvent := eventColumns[name]
+	_, isRecurrence := recurrenceColumn(name)
+	return isEvent || isRecurrence
+}
+
+func friendFromRecord(columns []string, rec []string) (Friend, error) {
+	friend := Friend{}
+	recurrences := map[EventType]Recurrence{}
+
+	for i, column := range columns {
+		value := strings.TrimSpace(rec[i])
+
+		switch column {
+		case "id":
+			friend.ID = value
+		case "last_name":
+			friend.LastName = value
+		case "first_name":
+			friend.FirstName = value
+		case "birth_date":
+			friend.BirthDate = value
+		case "email":
+			friend.Email = value
+		case "time_zone":
+			friend.TimeZone = value
+		case "tags":
+			friend.Tags = parseTags(value)
+		default:
+			if eventType, found := eventColumns[column]; found {
+				if value != "" {
+					friend.Events = append(friend.Events, Event{Type: eventType, Date: value, Recurrence: Yearly})
+				}
+				continue
+			}
+
+			if eventType, found := recurrenceColumn(column); found {
+				recurrence, err := parseRecurrence(value)
+				if err != nil {
+					return Friend{}, fmt.Errorf("%s: %w", column, err)
+				}
+				recurrences[eventType] = recurrence
+				continue
+			}
+
+			return Friend{}, fmt.Errorf("unknown column %q", column)
+		}
+	}
+
+	for i, event := range friend.Events {
+		friend.Events[i].Recurrence = recurrences[event.Type]
+	}
+
+	if friend.ID == "" {
+		friend.ID = friend.derivedID()
+	}
+
+	if _, err := friend.location(); err != nil {
+		return Friend{}, fmt.Errorf("invalid time zone %q for friend %s", friend.TimeZone, friend.ID)
+	}
+
+	return friend, nil
+}
+
+// parseTags splits a tags field such as "work; family" into lower case tags.
+func parseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ";") {
+		if tag = normalizeTag(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,60 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetFriendsFromTextFileWithHeaderAndTimeZone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "last_name, first_name, birth_date, email, time_zone\nDoe, John, 1982/10/08, john.doe@foobar.com, Asia/Tokyo\nAnn, Mary, 1975/09/11, mary.ann@foobar.com,\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repository := TextFileFriendsRepository{path: path}
+	want := []Friend{
+		{ID: "c4f77c3c468f1411", FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com", TimeZone: "Asia/Tokyo"},
+		{ID: "1ce90aa149d9fed9", FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
+	}
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(friends, want) {
+		t.Errorf("Expected %v but got %v", want, friends)
+	}
+}
+
+func TestGetFriendsFromTextFileWithInvalidTimeZone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "last_name, first_name, birth_date, email, time_zone\nDoe, John, 1982/10/08, john.doe@foobar.com, Mars/Olympus\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repository := TextFileFriendsRepository{path: path}
+
+	_, err := repository.GetFriends()
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestGetFriendsFromTextFileWithMissingColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "last_name, first_name, birth_date\nDoe, John, 1982/10/08\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repository := TextFileFriendsRepository{path: path}
+
+	_, err := repository.GetFriends()
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
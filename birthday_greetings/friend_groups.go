@@ -0,0 +1,81 @@
+package birthday_greetings
+
+// FriendGroup gives the friends matching Tags their own message options and
+// sender. A nil Options or Sender falls back to the scheduler's.
+type FriendGroup struct {
+	Name    string
+	Tags    TagExpression
+	Options *MessageOptions
+	Sender  Sender
+}
+
+// group returns the first configured group the friend belongs to.
+func (scheduler *Scheduler) group(friend Friend) (FriendGroup, bool) {
+	for _, group := range scheduler.config.Groups {
+		if group.Tags.Matches(friend) {
+			return group, true
+		}
+	}
+
+	return FriendGroup{}, false
+}
+
+func (scheduler *Scheduler) optionsFor(friend Friend) MessageOptions {
+	if group, found := scheduler.group(friend); found && group.Options != nil {
+		return *group.Options
+	}
+
+	return scheduler.config.Options
+}
+
+func (scheduler *Scheduler) senderFor(friend Friend) Sender {
+	if group, found := scheduler.group(friend); found && group.Sender != nil {
+		return group.Sender
+	}
+
+	return scheduler.sender()
+}
+
+// routeFor returns the sender delivering greetings: the organizer's group's
+// for a gift reminder, which is sent to them, and otherwise friend's group's.
+// Digests and organizers who are not friends belong to no group, so they go
+// through the scheduler's sender.
+func (scheduler *Scheduler) routeFor(friend Friend, greetings BirthdayGreetings) (Sender, error) {
+	switch {
+	case greetings.kind == DigestKind || len(scheduler.config.Groups) == 0:
+		return scheduler.sender(), nil
+	case greetings.kind != GiftReminderKind:
+		return scheduler.senderFor(friend), nil
+	}
+
+	friends, err := scheduler.repo.GetFriends()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, organizer := range friends {
+		if normalizeEmail(organizer.Email) == normalizeEmail(greetings.recipient) {
+			return scheduler.senderFor(organizer), nil
+		}
+	}
+
+	return scheduler.sender(), nil
+}
+
+// friendByID looks the friend up so retries go through the friend's group
+// and honour opt-outs recorded by address. Friends no longer in the
+// repository are only known by their ID.
+func (scheduler *Scheduler) friendByID(friendID string) (Friend, error) {
+	friends, err := scheduler.repo.GetFriends()
+	if err != nil {
+		return Friend{}, err
+	}
+
+	for _, friend := range friends {
+		if friend.identity() == friendID {
+			return friend, nil
+		}
+	}
+
+	return Friend{ID: friendID}, nil
+}
@@ -0,0 +1,275 @@
+package birthday_greetings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type channelSender string
+
+func (sender channelSender) Send(greetings BirthdayGreetings) (Receipt, error) {
+	return Receipt{Channel: string(sender)}, nil
+}
+
+func mustParseTags(t *testing.T, source string) TagExpression {
+	t.Helper()
+	expression, err := ParseTagExpression(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return expression
+}
+
+func writeTaggedFriendsFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "last_name,first_name,birth_date,email,tags\nDoe,John,1982/10/08,john.doe@foobar.com,work;contractors\nAnn,Mary,1975/10/08,mary.ann@foobar.com,work\nSmith,Jane,1990/05/15,jane.smith@example.com,family\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return path
+}
+
+func TestSchedulerOnlyGreetsFriendsMatchingTags(t *testing.T) {
+	scheduler := NewScheduler(stubFriendsRepository{friends: taggedFriends()}, SchedulerConfig{Tags: mustParseTags(t, "work AND NOT contractors")})
+
+	greetings, err := scheduler.Plan(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(greetings) != 1 || greetings[0].Recipient() != "mary.ann@foobar.com" {
+		t.Errorf("Expected only Mary to be greeted but got %v", greetings)
+	}
+}
+
+func TestSchedulerUsesGroupTemplatesAndSenders(t *testing.T) {
+	scheduler := NewScheduler(stubFriendsRepository{friends: taggedFriends()}, SchedulerConfig{
+		Sender: channelSender("default"),
+		Groups: []FriendGroup{
+			{Name: "contractors", Tags: mustParseTags(t, "contractors"), Sender: channelSender("contractors")},
+			{Name: "work", Tags: mustParseTags(t, "work"), Options: &MessageOptions{EventTemplates: map[EventType]EventTemplate{Birthday: {Template: "Happy birthday from the team, {{.FirstName}}!"}}}},
+		},
+	})
+
+	if err := scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{})
+	channels := map[string]string{}
+	messages := map[string]string{}
+	for _, receipt := range receipts {
+		channels[receipt.Recipient] = receipt.Channel
+		messages[receipt.Recipient] = receipt.Message
+	}
+
+	if channels["john.doe@foobar.com"] != "contractors" || channels["mary.ann@foobar.com"] != "default" {
+		t.Errorf("Expected John to go through the contractors sender but got %v", channels)
+	}
+
+	if messages["mary.ann@foobar.com"] != "Happy birthday from the team, Mary!" || strings.Contains(messages["john.doe@foobar.com"], "team") {
+		t.Errorf("Expected only the work group to use its template but got %v", messages)
+	}
+}
+
+func TestSchedulerRetriesThroughGroupSender(t *testing.T) {
+	scheduler := NewScheduler(stubFriendsRepository{friends: taggedFriends()[:1]}, SchedulerConfig{
+		Groups: []FriendGroup{{Name: "contractors", Tags: mustParseTags(t, "contractors"), Sender: failingSender{}}},
+	})
+	now := time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)
+	scheduler.Run(now)
+
+	failures, _ := scheduler.PendingFailures()
+	if len(failures) != 1 {
+		t.Fatalf("Expected John's greeting to fail but got %v", failures)
+	}
+
+	scheduler.config.Groups[0].Sender = channelSender("contractors")
+	receipt, err := scheduler.Retry(failures[0].MessageID, now.Add(time.Hour))
+	if err != nil || receipt.Channel != "contractors" {
+		t.Errorf("Expected the retry to use the group sender but got %v, '%v'", receipt, err)
+	}
+}
+
+func TestSchedulerRetryReportsFriendsLoadingErrors(t *testing.T) {
+	scheduler := NewScheduler(stubFriendsRepository{friends: taggedFriends()[:1]}, SchedulerConfig{Sender: failingSender{}})
+	now := time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)
+	scheduler.Run(now)
+	failures, _ := scheduler.PendingFailures()
+
+	scheduler.repo = stubFriendsRepository{err: errors.New("friends file is unreadable")}
+	if _, err := scheduler.Retry(failures[0].MessageID, now.Add(time.Hour)); err == nil || err.Error() != "friends file is unreadable" {
+		t.Errorf("Expected the loading error but got '%v'", err)
+	}
+}
+
+func TestSchedulerRoutesGiftRemindersThroughTheOrganizersGroup(t *testing.T) {
+	scheduler := NewScheduler(stubFriendsRepository{friends: taggedFriends()}, SchedulerConfig{
+		Sender:        channelSender("default"),
+		Groups:        []FriendGroup{{Name: "family", Tags: mustParseTags(t, "family"), Sender: channelSender("family")}, {Name: "work", Tags: mustParseTags(t, "work"), Sender: channelSender("work")}},
+		GiftReminders: GiftReminderConfig{LeadDays: 7, Recipients: []string{"jane.smith@example.com"}, Tags: "NOT contractors"},
+	})
+
+	if err := scheduler.Run(time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{})
+	if len(receipts) != 1 || receipts[0].FriendID != taggedFriends()[1].identity() {
+		t.Fatalf("Expected a single reminder about Mary but got %v", receipts)
+	}
+
+	if receipts[0].Channel != "family" {
+		t.Errorf("Expected the reminder to go through Jane's family sender but got %v", receipts[0].Channel)
+	}
+}
+
+func TestSchedulerKeepsDigestsAndOutsideOrganizersOutOfNegatedGroups(t *testing.T) {
+	scheduler := NewScheduler(stubFriendsRepository{friends: taggedFriends()}, SchedulerConfig{
+		Sender:        channelSender("default"),
+		Groups:        []FriendGroup{{Name: "outsiders", Tags: mustParseTags(t, "NOT work"), Sender: channelSender("outsiders")}},
+		GiftReminders: GiftReminderConfig{LeadDays: 7, Recipients: []string{"boss@example.com"}},
+		Digest:        DigestConfig{Recipients: []string{"office@example.com"}},
+	})
+
+	if err := scheduler.Run(time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{})
+	kinds := map[string]bool{}
+	for _, receipt := range receipts {
+		if receipt.Kind != "" {
+			kinds[receipt.Kind] = true
+			if receipt.Channel != "default" {
+				t.Errorf("Expected the %s to go through the default sender but got %v", receipt.Kind, receipt.Channel)
+			}
+		}
+	}
+
+	if !kinds[GiftReminderKind] || !kinds[DigestKind] {
+		t.Errorf("Expected a gift reminder and a digest but got %v", receipts)
+	}
+}
+
+func TestAPIFiltersFriendsByTags(t *testing.T) {
+	repository := TextFileFriendsRepository{path: writeTaggedFriendsFile(t)}
+	api := NewAPI(repository, NewScheduler(repository, SchedulerConfig{}))
+
+	response := serve(api, http.MethodGet, "/friends?tags=work+AND+NOT+contractors", "", nil)
+
+	var friends []Friend
+	if err := json.NewDecoder(response.Body).Decode(&friends); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.Code != http.StatusOK || !reflect.DeepEqual(firstNames(friends), []string{"Mary"}) {
+		t.Errorf("Expected to find Mary but got %d %v", response.Code, friends)
+	}
+
+	if response := serve(api, http.MethodGet, "/friends?tags=work+AND", "", nil); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 but got %d", response.Code)
+	}
+}
+
+func TestDashboardFiltersByTags(t *testing.T) {
+	repository := TextFileFriendsRepository{path: writeTaggedFriendsFile(t)}
+	scheduler := NewScheduler(repository, SchedulerConfig{})
+	dashboard := NewDashboard(repository, scheduler, 7)
+	dashboard.now = func() time.Time { return time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC) }
+	scheduler.Run(dashboard.now())
+	recorder := httptest.NewRecorder()
+
+	dashboard.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?tags=contractors", nil))
+
+	body := recorder.Body.String()
+	if recorder.Code != http.StatusOK || !strings.Contains(body, "john.doe@foobar.com") || strings.Contains(body, "mary.ann@foobar.com") {
+		t.Errorf("Expected only John's deliveries but got %d %s", recorder.Code, body)
+	}
+}
+
+func TestConfigSchedulerUsesGroups(t *testing.T) {
+	dir := t.TempDir()
+	mbox := filepath.Join(dir, "contractors.mbox")
+	path := writeConfig(t, fmt.Sprintf(`
+friends:
+  sources: [%s]
+  tags: work
+groups:
+  - name: contractors
+    tags: contractors
+    templates:
+      birthday:
+        template: Thanks for your help, {{.FirstName}}!
+    sender:
+      type: mbox
+      from: greetings@example.com
+      mbox: %s
+`, writeTaggedFriendsFile(t), mbox))
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	scheduler, err := config.Scheduler()
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if err := scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	archived, _ := os.ReadFile(mbox)
+	if !strings.Contains(string(archived), "Thanks for your help, John!") || strings.Contains(string(archived), "Mary") {
+		t.Errorf("Expected only John's greeting in the group mbox but got %q", archived)
+	}
+
+	if receipts, _ := scheduler.History().Query(HistoryQuery{}); len(receipts) != 2 {
+		t.Errorf("Expected greetings for John and Mary but got %v", receipts)
+	}
+}
+
+func TestLoadConfigValidatesGroups(t *testing.T) {
+	path := writeConfig(t, `
+friends:
+  sources: [birthdays.txt]
+  tags: work AND
+gift_reminders:
+  lead_days: 7
+  recipients: [boss@example.com]
+  tags: NOT
+groups:
+  - tags: (family
+    sender:
+      type: pigeon
+`)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatalf("Expected error to be raised but was not")
+	}
+
+	for _, want := range []string{"friends.tags", "gift_reminders.tags", "groups[0].name", "groups[0].tags", "groups[0].sender.type"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s but got '%v'", want, err.Error())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package birthday_greetings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// derivedID is a deterministic identifier computed from the friend's
+// original fields. It is used for friends loaded without an explicit ID, so
+// hand editing any of those fields gives the friend a new ID; add an id
+// column to keep it.
+func (friend Friend) derivedID() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strings.TrimSpace(friend.LastName),
+		strings.TrimSpace(friend.FirstName),
+		strings.TrimSpace(friend.BirthDate),
+		normalizeEmail(friend.Email),
+	}, "\x00")))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// identity returns the friend's ID, deriving one for friends built without
+// it. It never exposes personal data, so it is safe to log.
+func (friend Friend) identity() string {
+	if friend.ID != "" {
+		return friend.ID
+	}
+
+	return friend.derivedID()
+}
+
+func withIDs(friends []Friend) []Friend {
+	identified := make([]Friend, len(friends))
+	for i, friend := range friends {
+		friend.ID = friend.identity()
+		identified[i] = friend
+	}
+
+	return identified
+}
+
+func checkUniqueIDs(friends []Friend) error {
+	seen := map[string]bool{}
+	for _, friend := range friends {
+		if seen[friend.ID] {
+			return fmt.Errorf("duplicate friend id %q", friend.ID)
+		}
+		seen[friend.ID] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetFriendsFromTextFileWithExplicitIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "id, last_name, first_name, birth_date, email\nf-1, Doe, John, 1982/10/08, john.doe@foobar.com\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	friends, err := TextFileFriendsRepository{path: path}.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if friends[0].ID != "f-1" {
+		t.Errorf("Expected id 'f-1' but got '%s'", friends[0].ID)
+	}
+}
+
+func TestGetFriendsFromTextFileWithDuplicateIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "id, last_name, first_name, birth_date, email\nf-1, Doe, John, 1982/10/08, john.doe@foobar.com\nf-1, Ann, Mary, 1975/09/11, mary.ann@foobar.com\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err := TextFileFriendsRepository{path: path}.GetFriends()
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestSaveFriendsKeepsDerivedIDAfterEmailChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	repository := TextFileFriendsRepository{path: path}
+	friends, _ := TextFileFriendsRepository{path: "birthdays.txt"}.GetFriends()
+	id := friends[0].ID
+	friends[0].Email = "john@example.com"
+
+	if err := repository.SaveFriends(friends); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	reloaded, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if reloaded[0].ID != id || reloaded[0].Email != "john@example.com" {
+		t.Errorf("Expected id '%s' to survive the rewrite but got '%s'", id, reloaded[0].ID)
+	}
+}
+
+func TestSaveFriendsWithDuplicateIDs(t *testing.T) {
+	repository := TextFileFriendsRepository{path: filepath.Join(t.TempDir(), "friends.txt")}
+	friends := []Friend{
+		{ID: "f-1", FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+		{ID: "f-1", FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
+	}
+
+	err := repository.SaveFriends(friends)
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
@@ -0,0 +1,285 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// CSVFormat is the layout of a friends CSV file. Reading a file records its
+// format, and writing with that format reproduces the file byte for byte as
+// long as it was written in one consistent style: the same separator and
+// quoting on every line. Comment and blank lines before the first record are
+// kept in Preamble, those before a friend's record in Comments under the
+// friend's ID and those after the last record in Trailer. The comments of a
+// friend who is no longer written are dropped with the friend. The zero value
+// is the birthdays.txt format.
+type CSVFormat struct {
+	Preamble       []string
+	Comments       map[string][]string
+	Trailer        []string
+	Columns        []string
+	Header         bool
+	Separator      string
+	QuoteAll       bool
+	LineEnding     string
+	NoFinalNewline bool
+}
+
+// ReadFriendsCSV reads friends along with the format of the file they come
+// from.
+func ReadFriendsCSV(r io.Reader, options TextFileOptions) ([]Friend, CSVFormat, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, CSVFormat{}, err
+	}
+
+	text, err := options.decodeText(data)
+	if err != nil {
+		return nil, CSVFormat{}, err
+	}
+
+	friends, err := parseFriends(bytes.NewReader(text), options)
+	if err != nil {
+		return nil, CSVFormat{}, err
+	}
+
+	return friends, detectCSVFormat(string(text), options, friends), nil
+}
+
+// WriteFriendsCSV writes friends in format. Columns the friends need but the
+// format lacks are added, with a header naming them.
+func WriteFriendsCSV(w io.Writer, friends []Friend, format CSVFormat) error {
+	data, err := formatFriends(friends, format)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// ReadFriendsJSON reads friends from a JSON array as written by
+// WriteFriendsJSON, deriving missing IDs.
+func ReadFriendsJSON(r io.Reader) ([]Friend, error) {
+	var friends []Friend
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&friends); err != nil {
+		return nil, err
+	}
+
+	friends = withIDs(friends)
+	return friends, checkUniqueIDs(friends)
+}
+
+// WriteFriendsJSON writes friends as an indented JSON array, in field order
+// and without escaping HTML characters, so that its output reads back and
+// writes out unchanged.
+func WriteFriendsJSON(w io.Writer, friends []Friend) error {
+	friends = withIDs(friends)
+	if err := checkUniqueIDs(friends); err != nil {
+		return err
+	}
+
+	if friends == nil {
+		friends = []Friend{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(friends)
+}
+
+func formatFriends(friends []Friend, format CSVFormat) ([]byte, error) {
+	friends = withIDs(friends)
+	if err := checkUniqueIDs(friends); err != nil {
+		return nil, err
+	}
+
+	columns := format.columnsFor(friends)
+
+	lines := slices.Clone(format.Preamble)
+	if format.Header || !slices.Equal(columns, defaultFriendColumns) {
+		lines = append(lines, format.record(columns))
+	}
+
+	for _, friend := range friends {
+		lines = append(lines, format.Comments[friend.ID]...)
+		lines = append(lines, format.record(recordFor(columns, friend)))
+	}
+
+	lines = append(lines, format.Trailer...)
+
+	if len(lines) == 0 {
+		return []byte{}, nil
+	}
+
+	output := strings.Join(lines, format.lineEnding())
+	if !format.NoFinalNewline {
+		output += format.lineEnding()
+	}
+
+	return []byte(output), nil
+}
+
+// columnsFor keeps the format's columns and adds those the friends need: the
+// id column first, the others last.
+func (format CSVFormat) columnsFor(friends []Friend) []string {
+	if format.Columns == nil {
+		return columnsFor(friends)
+	}
+
+	columns := slices.Clone(format.Columns)
+	for _, needed := range columnsFor(friends) {
+		if slices.Contains(columns, needed) {
+			continue
+		}
+
+		if needed == "id" {
+			columns = append([]string{needed}, columns...)
+		} else {
+			columns = append(columns, needed)
+		}
+	}
+
+	return columns
+}
+
+// record joins fields with the separator, quoting the fields that would not
+// read back unchanged, or all of them with QuoteAll.
+func (format CSVFormat) record(fields []string) string {
+	separator := format.separator()
+	delimiter, _ := utf8.DecodeRuneInString(separator)
+
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		if format.QuoteAll || field != strings.TrimSpace(field) || strings.ContainsAny(field, string(delimiter)+"\"\r\n") {
+			field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+		quoted[i] = field
+	}
+
+	return strings.Join(quoted, separator)
+}
+
+func (format CSVFormat) separator() string {
+	if format.Separator == "" {
+		return ", "
+	}
+
+	return format.Separator
+}
+
+func (format CSVFormat) lineEnding() string {
+	if format.LineEnding == "" {
+		return "\n"
+	}
+
+	return format.LineEnding
+}
+
+// detectCSVFormat reads the layout of a friends file from its first lines and
+// where its comment and blank lines are. The text must parse as a friends
+// file, holding friends.
+func detectCSVFormat(text string, options TextFileOptions, friends []Friend) CSVFormat {
+	format := CSVFormat{Columns: defaultFriendColumns, Separator: string(options.delimiter()) + " ", LineEnding: "\n"}
+
+	if strings.Contains(text, "\r\n") {
+		format.LineEnding = "\r\n"
+	}
+
+	format.NoFinalNewline = text != "" && !strings.HasSuffix(text, "\n")
+
+	lines := []string{}
+	comments := [][]string{}
+	pending := []string{}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || (options.Comment != 0 && strings.HasPrefix(line, string(options.Comment))) {
+			if text != "" {
+				pending = append(pending, line)
+			}
+			continue
+		}
+		lines = append(lines, line)
+		comments = append(comments, pending)
+		pending = nil
+	}
+
+	if len(lines) == 0 {
+		format.Preamble = pending
+		return format
+	}
+
+	format.Preamble = comments[0]
+	format.Trailer = pending
+
+	reader := csv.NewReader(strings.NewReader(lines[0]))
+	reader.TrimLeadingSpace = true
+	reader.Comma = options.delimiter()
+	if first, err := reader.Read(); err == nil && isFriendColumn(strings.ToLower(strings.TrimSpace(first[0]))) {
+		format.Header = true
+		format.Columns = make([]string, len(first))
+		for i, name := range first {
+			format.Columns[i] = strings.ToLower(strings.TrimSpace(name))
+		}
+
+		if len(lines) > 1 {
+			lines = lines[1:]
+			comments = comments[1:]
+		}
+	} else {
+		comments[0] = nil
+	}
+
+	// Records spanning several lines would shift the comments onto the wrong
+	// friends, so they are only kept when each friend takes one line.
+	if len(comments) == len(friends) {
+		for i, friend := range friends {
+			if len(comments[i]) > 0 {
+				if format.Comments == nil {
+					format.Comments = map[string][]string{}
+				}
+				format.Comments[friend.ID] = comments[i]
+			}
+		}
+	}
+
+	fields := rawFields(lines[0], options.delimiter())
+	format.QuoteAll = true
+	for i, field := range fields {
+		trimmed := strings.TrimLeft(field, " \t")
+		if i == 1 {
+			format.Separator = string(options.delimiter()) + field[:len(field)-len(trimmed)]
+		}
+		format.QuoteAll = format.QuoteAll && strings.HasPrefix(trimmed, `"`)
+	}
+
+	return format
+}
+
+// rawFields splits a CSV line at the delimiters outside quotes, keeping the
+// fields exactly as written.
+func rawFields(line string, delimiter rune) []string {
+	fields := []string{}
+	inQuotes := false
+	start := 0
+
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == delimiter && !inQuotes:
+			fields = append(fields, line[start:i])
+			start = i + len(string(delimiter))
+		}
+	}
+
+	return append(fields, line[start:])
+}
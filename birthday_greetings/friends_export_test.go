@@ -0,0 +1,157 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVRoundTripReproducesInput(t *testing.T) {
+	birthdays, _ := os.ReadFile("birthdays.txt")
+
+	tests := []struct {
+		name    string
+		input   string
+		options TextFileOptions
+	}{
+		{"birthdays.txt", string(birthdays), TextFileOptions{}},
+		{"commas quotes and accents", "last_name, first_name, birth_date, email, time_zone\n" +
+			"\"Doe, Jr.\", John, 1982/10/08, john.doe@foobar.com, Europe/Paris\n" +
+			"\"O\"\"Brien\", Zoë, 1975/09/11, zoe@foobar.com, \n" +
+			"Müller, François, 2001/02/03, \"francois,muller@foobar.com\", Asia/Tokyo\n", TextFileOptions{}},
+		{"quote all with CRLF", "\"Doe\", \"John\", \"1982/10/08\", \"john.doe@foobar.com\"\r\n\"Ann, Mary\", \"Zoë\", \"1975/09/11\", \"mary.ann@foobar.com\"\r\n", TextFileOptions{}},
+		{"compact separator without final newline", "Doe,John,1982/10/08,john.doe@foobar.com\n\"Ann, \"\"Mary\"\"\",Zoë,1975/09/11,mary.ann@foobar.com", TextFileOptions{}},
+		{"semicolons and reordered header", "email; birth_date; first_name; last_name\nmary.ann@foobar.com; 1975/09/11; Mary; \"Ann; Smith\"\n", TextFileOptions{Delimiter: ';'}},
+		{"header with ids", "id, last_name, first_name, birth_date, email\nfriend-1, Doe, John, 1982/10/08, john.doe@foobar.com\n", TextFileOptions{}},
+		{"tabs", "Doe\tJohn\t1982/10/08\tjohn.doe@foobar.com\nAnn\t\"Mary\tJo\"\t1975/09/11\tmary.ann@foobar.com\n", TextFileOptions{Delimiter: '\t'}},
+		{"multi-byte delimiter", "Doe¦John¦1982/10/08¦john.doe@foobar.com\n\"Ann¦Smith\"¦Mary¦1975/09/11¦mary.ann@foobar.com\n", TextFileOptions{Delimiter: '¦'}},
+		{"leading comments", "# friends\n\nDoe, John, 1982/10/08, john.doe@foobar.com\n", TextFileOptions{Comment: '#'}},
+		{"interleaved comments", "# friends\nlast_name, first_name, birth_date, email\n# work\nDoe, John, 1982/10/08, john.doe@foobar.com\n\n# family\nAnn, Mary, 1975/09/11, mary.ann@foobar.com\n\n# end\n", TextFileOptions{Comment: '#'}},
+	}
+
+	for _, test := range tests {
+		friends, format, err := ReadFriendsCSV(strings.NewReader(test.input), test.options)
+		if err != nil {
+			t.Errorf("%s: Expected no error but got '%v'", test.name, err.Error())
+			continue
+		}
+
+		var output bytes.Buffer
+		if err := WriteFriendsCSV(&output, friends, format); err != nil {
+			t.Errorf("%s: Expected no error but got '%v'", test.name, err.Error())
+			continue
+		}
+
+		if output.String() != test.input {
+			t.Errorf("%s: Expected %q but got %q", test.name, test.input, output.String())
+		}
+	}
+}
+
+func TestCSVRoundTripPreservesFriends(t *testing.T) {
+	input := "\"Doe, Jr.\", \"Jean \"\"Johnny\"\"\", 1982/10/08, john.doe@foobar.com\n"
+	friends, format, _ := ReadFriendsCSV(strings.NewReader(input), TextFileOptions{})
+
+	if friends[0].LastName != "Doe, Jr." || friends[0].FirstName != `Jean "Johnny"` {
+		t.Errorf("Expected the quoted names to read back but got %v", friends[0])
+	}
+
+	var output bytes.Buffer
+	WriteFriendsCSV(&output, friends, format)
+	again, _, _ := ReadFriendsCSV(&output, TextFileOptions{})
+
+	if !reflect.DeepEqual(again, friends) {
+		t.Errorf("Expected %v but got %v", friends, again)
+	}
+}
+
+func TestWriteFriendsCSVAddsMissingColumns(t *testing.T) {
+	friends, format, _ := ReadFriendsCSV(strings.NewReader("Doe, John, 1982/10/08, john.doe@foobar.com\n"), TextFileOptions{})
+	friends[0].TimeZone = "Europe/Paris"
+
+	var output bytes.Buffer
+	WriteFriendsCSV(&output, friends, format)
+
+	want := "last_name, first_name, birth_date, email, time_zone\nDoe, John, 1982/10/08, john.doe@foobar.com, Europe/Paris\n"
+	if output.String() != want {
+		t.Errorf("Expected %q but got %q", want, output.String())
+	}
+}
+
+func TestSaveFriendsKeepsExistingLayout(t *testing.T) {
+	input := "\"Doe\",\"John\",\"1982/10/08\",\"john.doe@foobar.com\"\r\n"
+	path := writeEncodedFriendsFile(t, []byte(input))
+	repo := NewTextFileFriendsRepository(path, TextFileOptions{})
+
+	friends, _ := repo.GetFriends()
+	friends = append(friends, Friend{LastName: "Ann", FirstName: "Mary", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"})
+	if err := repo.SaveFriends(friends); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	got, _ := os.ReadFile(path)
+	want := input + "\"Ann\",\"Mary\",\"1975/09/11\",\"mary.ann@foobar.com\"\r\n"
+	if string(got) != want {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+}
+
+func TestSaveFriendsKeepsInterleavedComments(t *testing.T) {
+	input := "# work\nDoe, John, 1982/10/08, john.doe@foobar.com\n# family\nAnn, Mary, 1975/09/11, mary.ann@foobar.com\n# end\n"
+	path := writeEncodedFriendsFile(t, []byte(input))
+	repo := NewTextFileFriendsRepository(path, TextFileOptions{Comment: '#'})
+
+	friends, _ := repo.GetFriends()
+	friends = append(friends[:1], Friend{LastName: "Smith", FirstName: "Jane", BirthDate: "1990/05/15", Email: "jane.smith@example.com"})
+	if err := repo.SaveFriends(friends); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "# work\nDoe, John, 1982/10/08, john.doe@foobar.com\nSmith, Jane, 1990/05/15, jane.smith@example.com\n# end\n"
+	if string(got) != want {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+}
+
+func TestJSONRoundTripReproducesInput(t *testing.T) {
+	friends := []Friend{
+		{LastName: "Doe, Jr.", FirstName: `Jean "Johnny"`, BirthDate: "1982/10/08", Email: "john.doe@foobar.com", TimeZone: "Europe/Paris"},
+		{LastName: "Müller & Söhne", FirstName: "Zoë <3", BirthDate: "06/24", Email: "zoe@foobar.com", Events: []Event{{Type: NameDay, Date: "07/06"}}},
+	}
+
+	var first bytes.Buffer
+	if err := WriteFriendsJSON(&first, friends); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !strings.Contains(first.String(), `"last_name": "Müller & Söhne"`) || !strings.Contains(first.String(), `"first_name": "Jean \"Johnny\""`) {
+		t.Errorf("Expected readable JSON but got %s", first.String())
+	}
+
+	read, err := ReadFriendsJSON(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !reflect.DeepEqual(read, withIDs(friends)) {
+		t.Errorf("Expected %v but got %v", withIDs(friends), read)
+	}
+
+	var second bytes.Buffer
+	WriteFriendsJSON(&second, read)
+	if second.String() != first.String() {
+		t.Errorf("Expected %q but got %q", first.String(), second.String())
+	}
+}
+
+func TestWriteFriendsJSONWithoutFriends(t *testing.T) {
+	var output bytes.Buffer
+	WriteFriendsJSON(&output, nil)
+
+	if output.String() != "[]\n" {
+		t.Errorf("Expected an empty array but got %q", output.String())
+	}
+}
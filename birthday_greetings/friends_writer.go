@@ -0,0 +1,133 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SaveFriends rewrites the friends file in the repository's dialect and
+// encoding, without a byte order mark. An existing file keeps its layout and
+// comments; new files only holding the four default columns are written
+// without a header, like birthdays.txt, otherwise a header names the optional
+// columns in use. IDs are written whenever they can no longer be derived from
+// the other fields, so they survive rewrites.
+func (repo TextFileFriendsRepository) SaveFriends(friends []Friend) error {
+	data, err := formatFriends(friends, repo.existingFormat())
+	if err != nil {
+		return err
+	}
+
+	data, err = repo.options.encodeText(data)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(repo.path, data)
+}
+
+// existingFormat returns the format of the current friends file, or the
+// default format in the repository's delimiter when there is no readable file.
+func (repo TextFileFriendsRepository) existingFormat() CSVFormat {
+	if file, err := os.Open(repo.path); err == nil {
+		defer file.Close()
+		if _, format, err := ReadFriendsCSV(file, repo.options); err == nil {
+			return format
+		}
+	}
+
+	return CSVFormat{Separator: string(repo.options.delimiter()) + " "}
+}
+
+func columnsFor(friends []Friend) []string {
+	columns := append([]string{}, defaultFriendColumns...)
+	used := map[string]bool{}
+
+	for _, friend := range friends {
+		if friend.ID != friend.derivedID() {
+			used["id"] = true
+		}
+
+		if friend.TimeZone != "" {
+			used["time_zone"] = true
+		}
+
+		if len(friend.Tags) > 0 {
+			used["tags"] = true
+		}
+
+		for _, event := range friend.Events {
+			used[string(event.Type)] = true
+			if event.Recurrence != Yearly {
+				used[string(event.Type)+recurrenceColumnSuffix] = true
+			}
+		}
+	}
+
+	if used["id"] {
+		columns = append([]string{"id"}, columns...)
+	}
+
+	for _, optional := range []string{"time_zone", "tags", "work_anniversary", "work_anniversary_recurrence", "wedding_anniversary", "wedding_anniversary_recurrence", "name_day", "name_day_recurrence"} {
+		if used[optional] {
+			columns = append(columns, optional)
+		}
+	}
+
+	return columns
+}
+
+func recordFor(columns []string, friend Friend) []string {
+	record := make([]string, len(columns))
+
+	for i, column := range columns {
+		switch column {
+		case "id":
+			record[i] = friend.ID
+		case "last_name":
+			record[i] = friend.LastName
+		case "first_name":
+			record[i] = friend.FirstName
+		case "birth_date":
+			record[i] = friend.BirthDate
+		case "email":
+			record[i] = friend.Email
+		case "time_zone":
+			record[i] = friend.TimeZone
+		case "tags":
+			record[i] = strings.Join(friend.Tags, ";")
+		default:
+			for _, event := range friend.Events {
+				if string(event.Type) == column {
+					record[i] = event.Date
+				}
+
+				if string(event.Type)+recurrenceColumnSuffix == column {
+					record[i] = event.Recurrence.String()
+				}
+			}
+		}
+	}
+
+	return record
+}
+
+func writeFileAtomically(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
@@ -0,0 +1,43 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveFriendsWritesBirthdaysFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	friends, _ := TextFileFriendsRepository{path: "birthdays.txt"}.GetFriends()
+
+	if err := (TextFileFriendsRepository{path: path}).SaveFriends(friends); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	want, _ := os.ReadFile("birthdays.txt")
+	got, _ := os.ReadFile(path)
+	if string(got) != string(want) {
+		t.Errorf("Expected '%s' but got '%s'", want, got)
+	}
+}
+
+func TestSaveFriendsWithOptionalColumns(t *testing.T) {
+	repository := TextFileFriendsRepository{path: filepath.Join(t.TempDir(), "friends.txt")}
+	friends := []Friend{
+		{FirstName: "John", LastName: "Doe, Jr.", BirthDate: "1982/10/08", Email: "john.doe@foobar.com", TimeZone: "Europe/Paris", Events: []Event{{Type: NameDay, Date: "06/24"}}},
+	}
+
+	if err := repository.SaveFriends(friends); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	got, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if want := withIDs(friends); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v but got %v", want, got)
+	}
+}
@@ -1,57 +1,133 @@
 package birthday_greetings
 
 import (
+	"bytes"
 	"encoding/csv"
 	"errors"
-	"fmt"
+	"io"
 	"os"
-	"strings"
+	"time"
 )
 
 type Friend struct {
-	LastName string
-	FirstName string
-	BirthDate string
-	Email string
+	ID string `json:"id"`
+	LastName string `json:"last_name"`
+	FirstName string `json:"first_name"`
+	BirthDate string `json:"birth_date"`
+	Email string `json:"email"`
+	Source string `json:"source,omitempty"`
+	TimeZone string `json:"time_zone,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	Events []Event `json:"events,omitempty"`
 }
 
 type BirthdayGreetings struct {
 	title string
 	message string
+	recipient string
+	headers map[string]string
+	messageID string
+	kind string
+	mentions []string
 }
 
 type FriendsRepository interface {
 	GetFriends() ([]Friend, error)
 }
 
+type FriendsStore interface {
+	FriendsRepository
+	SaveFriends(friends []Friend) error
+}
+
 type TextFileFriendsRepository struct {
 	path string
+	options TextFileOptions
+}
+
+func NewTextFileFriendsRepository(path string, options TextFileOptions) TextFileFriendsRepository {
+	return TextFileFriendsRepository{path: path, options: options}
+}
+
+// BuildBirthdayMessage builds today's greeting like the scheduler builds
+// it: it takes the age the friend is turning into account and, with
+// variants, uses the variant picked for the friend this year.
+func (friend Friend) BuildBirthdayMessage(variants ...MessageVariant) (BirthdayGreetings, error) {
+	return friend.BuildBirthdayMessageOn(time.Now(), MessageOptions{Variants: variants})
 }
 
-func (friend Friend) BuildBirthdayMessage() (BirthdayGreetings, error) {
+func (friend Friend) validate() error {
 	if friend.BirthDate == "" {
-		return BirthdayGreetings{}, errors.New("birth date is empty")
+		return errors.New("birth date is empty")
 	}
 
 	if friend.Email == "" {
-		return BirthdayGreetings{}, errors.New("email is empty")
+		return errors.New("email is empty")
 	}
 
 	if friend.FirstName == "" {
-		return BirthdayGreetings{}, errors.New("first name is empty")
+		return errors.New("first name is empty")
 	}
 
 	if friend.LastName == "" {
-		return BirthdayGreetings{}, errors.New("last name is empty")
+		return errors.New("last name is empty")
 	}
 
-	return BirthdayGreetings{
-		title: "Happy Birthday",
-		message: fmt.Sprintf("Happy birthday, dear %s %s!", friend.FirstName, friend.LastName),
-	}, nil
+	return nil
+}
+
+func (greetings BirthdayGreetings) Title() string {
+	return greetings.title
+}
+
+func (greetings BirthdayGreetings) Message() string {
+	return greetings.message
+}
+
+func (greetings BirthdayGreetings) Recipient() string {
+	return greetings.recipient
+}
+
+func (greetings BirthdayGreetings) Send() (Receipt, error) {
+	return greetings.SendWith(NoopSender{})
 }
 
-func (greetings BirthdayGreetings) Send() error {
+// SendWith delivers the greetings through sender. The returned receipt is
+// filled in even when sending fails, with a failed status.
+func (greetings BirthdayGreetings) SendWith(sender Sender) (Receipt, error) {
+	if greetings.messageID == "" {
+		greetings.messageID = newMessageID()
+	}
+
+	var receipt Receipt
+	err := greetings.validate()
+	if err == nil {
+		receipt, err = sender.Send(greetings)
+	}
+
+	receipt.MessageID = greetings.messageID
+	receipt.Recipient = greetings.recipient
+	receipt.Title = greetings.title
+	receipt.Message = greetings.message
+	receipt.Headers = greetings.headers
+	receipt.Kind = greetings.kind
+	receipt.Mentions = greetings.mentions
+	if receipt.SentAt.IsZero() {
+		receipt.SentAt = time.Now()
+	}
+
+	receipt.Status = Sent
+	if err != nil {
+		receipt.Error = err.Error()
+		if archiveErr := (*ArchiveError)(nil); !errors.As(err, &archiveErr) {
+			receipt.Status = Failed
+		}
+	}
+
+	return receipt, err
+}
+
+func (greetings BirthdayGreetings) validate() error {
 	if greetings.title == "" {
 		return errors.New("title is empty")
 	}
@@ -64,32 +140,54 @@ func (greetings BirthdayGreetings) Send() error {
 }
 
 func (repo TextFileFriendsRepository) GetFriends() ([]Friend, error) {
-	data, err := os.Open(repo.path)
+	data, err := os.ReadFile(repo.path)
 	if err != nil {
 		return nil, err
 	}
 
-	defer data.Close()
+	return repo.parseFriendsFile(data)
+}
+
+// parseFriendsFile reads friends from data, the content of the friends file.
+func (repo TextFileFriendsRepository) parseFriendsFile(data []byte) ([]Friend, error) {
+	friends, _, err := ReadFriendsCSV(bytes.NewReader(data), repo.options)
+	return friends, err
+}
 
+// parseFriends reads friends from UTF-8 CSV data in the friends file format.
+func parseFriends(data io.Reader, options TextFileOptions) ([]Friend, error) {
 	csv := csv.NewReader(data)
 	csv.TrimLeadingSpace = true
-	csv.FieldsPerRecord = 4
+	csv.FieldsPerRecord = 0
+	csv.Comma = options.delimiter()
+	csv.Comment = options.Comment
 
 	rows, err := csv.ReadAll()
 		if err != nil {
 			return nil, err
 		}
 
+	normalizeFields(rows)
+
+	columns, rows, err := friendColumns(rows)
+	if err != nil {
+		return nil, err
+	}
+
 	friends := make([]Friend, 0, len(rows))
 
 	for _, rec := range rows {
-			friends = append(friends, Friend{
-				LastName:  strings.TrimSpace(rec[0]),
-				FirstName: strings.TrimSpace(rec[1]),
-				BirthDate: strings.TrimSpace(rec[2]),
-				Email:     strings.TrimSpace(rec[3]),
-			})
+			friend, err := friendFromRecord(columns, rec)
+			if err != nil {
+				return nil, err
+			}
+
+			friends = append(friends, friend)
 		}
 
+	if err := checkUniqueIDs(friends); err != nil {
+		return nil, err
+	}
+
 	return friends, nil
 }
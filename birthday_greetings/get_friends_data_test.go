@@ -1,8 +1,10 @@
 package birthday_greetings
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGetFriendsFromTextFileWithInvalidFilePath(t *testing.T) {
@@ -19,8 +21,8 @@ func TestGetFriendsFromTextFile(t *testing.T) {
 	path := "birthdays.txt"
 	repository := TextFileFriendsRepository{path: path}
 	want := []Friend{
-		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
-		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
+		{ID: "c4f77c3c468f1411", FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+		{ID: "1ce90aa149d9fed9", FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
 	}
 
 	friends, err := repository.GetFriends()
@@ -103,13 +105,23 @@ func TestBuildBirthdayMessageWithoutFriendEmail(t *testing.T) {
 	}
 }
 
+func TestBuildBirthdayMessageWithInvalidBirthdate(t *testing.T) {
+	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "15/05/1990", Email: "jane.smith@example.com"}
+
+	_, err := friend.BuildBirthdayMessage()
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
 func TestBuildBirthdayMessageWithAFriendWithAllData(t *testing.T) {
 	friend := Friend{FirstName: "Jane", LastName: "Doe", BirthDate: "1990/05/15", Email: "jane.smith@example.com"}
 
 	got, err := friend.BuildBirthdayMessage()
 	want := BirthdayGreetings{
 		title: "Happy Birthday",
-		message: "Happy birthday, dear Jane Doe!",
+		message: fmt.Sprintf("Happy birthday, dear Jane Doe! Congratulations on turning %d!", time.Now().Year()-1990),
 	}
 
 	if err != nil {
@@ -123,7 +135,7 @@ func TestBuildBirthdayMessageWithAFriendWithAllData(t *testing.T) {
 
 func TestSendBirthdayGreetings(t *testing.T) {
 	birthdayGreetings := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear Jane Doe!"}
-	err := birthdayGreetings.Send()
+	_, err := birthdayGreetings.Send()
 
 	if err != nil {
 		t.Errorf("Expected no error but got '%v'", err.Error())
@@ -132,7 +144,7 @@ func TestSendBirthdayGreetings(t *testing.T) {
 
 func TestSendBirthdayGreetingsWithoutTitle(t *testing.T) {
 	birthdayGreetings := BirthdayGreetings{title: "", message: "Happy birthday, dear Jane Doe!"}
-	err := birthdayGreetings.Send()
+	_, err := birthdayGreetings.Send()
 
 	if err == nil {
 		t.Errorf("Expected error to be raised but was not")
@@ -145,7 +157,7 @@ func TestSendBirthdayGreetingsWithoutTitle(t *testing.T) {
 
 func TestSendBirthdayGreetingsWithoutMessage(t *testing.T) {
 	birthdayGreetings := BirthdayGreetings{title: "Happy Birthday", message: ""}
-	err := birthdayGreetings.Send()
+	_, err := birthdayGreetings.Send()
 
 	if err == nil {
 		t.Errorf("Expected error to be raised but was not")
@@ -0,0 +1,121 @@
+package birthday_greetings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const GiftReminderKind = "gift_reminder"
+
+const defaultGiftReminderTitle = "Gift reminder: {{.FirstName}} {{.LastName}}'s birthday"
+
+const defaultGiftReminderMessage = "{{.FirstName}} {{.LastName}}'s birthday is on {{.Date}}, in {{.DaysLeft}} days{{if .Age}}, turning {{.Age}}{{end}}. Time to get a present!"
+
+// GiftReminderConfig sends the organizer, or every address in Recipients, a
+// reminder LeadDays days before each birthday. A zero LeadDays disables
+// reminders. Tags limits reminders to the birthdays of friends matching a
+// tag expression.
+type GiftReminderConfig struct {
+	LeadDays   int      `yaml:"lead_days"`
+	Recipients []string `yaml:"recipients"`
+	Title      string   `yaml:"title"`
+	Template   string   `yaml:"template"`
+	Tags       string   `yaml:"tags"`
+}
+
+type giftReminderData struct {
+	FirstName string
+	LastName  string
+	Age       int
+	Date      string
+	DaysLeft  int
+}
+
+// giftReminders plans the reminders about the friend's birthday LeadDays
+// days after local. A reminder already in the history or held for delivery is
+// not planned again.
+func (scheduler *Scheduler) giftReminders(friend Friend, local time.Time) ([]scheduledGreeting, error) {
+	config := scheduler.config.GiftReminders
+	if config.LeadDays <= 0 || len(config.Recipients) == 0 {
+		return nil, nil
+	}
+
+	if config.Tags != "" {
+		tags, err := ParseTagExpression(config.Tags)
+		if err != nil || !tags.Matches(friend) {
+			return nil, err
+		}
+	}
+
+	birthday := startOfDay(local).AddDate(0, 0, config.LeadDays)
+	occurs, err := friend.AllEvents()[0].OccursOn(birthday)
+	if err != nil || !occurs {
+		return nil, err
+	}
+
+	if err := friend.validate(); err != nil {
+		return nil, err
+	}
+
+	birth, err := parseBirthDate(friend.BirthDate)
+	if err != nil {
+		return nil, err
+	}
+
+	data := giftReminderData{
+		FirstName: friend.FirstName,
+		LastName:  friend.LastName,
+		Age:       birth.ageOn(birthday),
+		Date:      birthday.Format("Monday, January 2"),
+		DaysLeft:  config.LeadDays,
+	}
+
+	title, err := renderTemplate("gift reminder title", orDefault(config.Title, defaultGiftReminderTitle), data)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := renderTemplate("gift reminder message", orDefault(config.Template, defaultGiftReminderMessage), data)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := []scheduledGreeting{}
+	for _, recipient := range config.Recipients {
+		if normalizeEmail(recipient) == normalizeEmail(friend.Email) {
+			continue
+		}
+
+		messageID := giftReminderID(friend, birthday, recipient)
+		sent, err := scheduler.isPlannedOrSent(messageID)
+		if err != nil {
+			return planned, err
+		}
+
+		if sent {
+			continue
+		}
+
+		planned = append(planned, scheduledGreeting{
+			friend: friend,
+			greetings: BirthdayGreetings{
+				title:     title,
+				message:   message,
+				recipient: recipient,
+				messageID: messageID,
+				kind:      GiftReminderKind,
+			},
+			local: local,
+		})
+	}
+
+	return planned, nil
+}
+
+// giftReminderID derives the message ID from the friend, the birthday and
+// the recipient, so the ledger tells whether a reminder was already sent.
+func giftReminderID(friend Friend, birthday time.Time, recipient string) string {
+	sum := sha256.Sum256([]byte(friend.identity() + "\x00" + birthday.Format(birthDateLayout) + "\x00" + normalizeEmail(recipient)))
+	return "gift-" + hex.EncodeToString(sum[:12]) + "@birthday-greetings"
+}
@@ -0,0 +1,83 @@
+package birthday_greetings
+
+import (
+	"testing"
+	"time"
+)
+
+func giftReminderScheduler(sender Sender) *Scheduler {
+	repository := stubFriendsRepository{friends: reminderFriends()}
+	return NewScheduler(repository, SchedulerConfig{
+		Sender:        sender,
+		GiftReminders: GiftReminderConfig{LeadDays: 7, Recipients: []string{"organizer@example.com", "jane.smith@example.com"}},
+	})
+}
+
+func TestSchedulerSendsGiftRemindersAheadOfBirthdays(t *testing.T) {
+	scheduler := giftReminderScheduler(nil)
+
+	greetings, err := scheduler.Plan(time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(greetings) != 4 {
+		t.Fatalf("Expected a reminder per recipient for John and Mary but got %v", greetings)
+	}
+
+	want := "John Doe's birthday is on Tuesday, October 8, in 7 days, turning 42. Time to get a present!"
+	if greetings[0].Recipient() != "organizer@example.com" || greetings[0].Title() != "Gift reminder: John Doe's birthday" || greetings[0].Message() != want {
+		t.Errorf("Expected %q for the organizer but got %v", want, greetings[0])
+	}
+}
+
+func TestSchedulerSendsGiftRemindersOnce(t *testing.T) {
+	scheduler := giftReminderScheduler(nil)
+	now := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	scheduler.Run(now)
+	scheduler.Run(now.Add(time.Hour))
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{})
+	if len(receipts) != 4 {
+		t.Errorf("Expected 4 reminders in the ledger but got %d", len(receipts))
+	}
+
+	for _, receipt := range receipts {
+		if receipt.Kind != GiftReminderKind {
+			t.Errorf("Expected a gift reminder receipt but got %v", receipt)
+		}
+	}
+}
+
+func TestSchedulerDoesNotResendFailedGiftReminders(t *testing.T) {
+	scheduler := giftReminderScheduler(failingSender{})
+	now := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	if err := scheduler.Run(now); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	if greetings, _ := scheduler.Plan(now.Add(time.Hour)); len(greetings) != 0 {
+		t.Errorf("Expected failed reminders to be left to retries but got %v", greetings)
+	}
+
+	failures, _ := scheduler.PendingFailures()
+	scheduler.config.Sender = NoopSender{}
+	receipt, err := scheduler.Retry(failures[0].MessageID, now.Add(time.Hour))
+	if err != nil || receipt.Kind != GiftReminderKind {
+		t.Errorf("Expected the retry to stay a gift reminder but got %v, %v", receipt, err)
+	}
+}
+
+func TestGiftRemindersSkipTheCelebrant(t *testing.T) {
+	repository := stubFriendsRepository{friends: reminderFriends()[:1]}
+	scheduler := NewScheduler(repository, SchedulerConfig{
+		GiftReminders: GiftReminderConfig{LeadDays: 3, Recipients: []string{"John.Doe@foobar.com"}, Template: "Buy {{.FirstName}} a present"},
+	})
+
+	greetings, _ := scheduler.Plan(time.Date(2024, 10, 5, 9, 0, 0, 0, time.UTC))
+	if len(greetings) != 0 {
+		t.Errorf("Expected no reminder about John's own birthday but got %v", greetings)
+	}
+}
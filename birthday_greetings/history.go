@@ -0,0 +1,209 @@
+package birthday_greetings
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryStore keeps every delivery receipt so past sends can be looked up.
+type HistoryStore interface {
+	Record(receipt Receipt) error
+	Query(query HistoryQuery) ([]Receipt, error)
+}
+
+// HistoryQuery filters receipts. Zero fields match everything; From is
+// inclusive and To is exclusive. FriendID also matches the digests naming the
+// friend.
+type HistoryQuery struct {
+	FriendID  string
+	MessageID string
+	From      time.Time
+	To        time.Time
+	Status    DeliveryStatus
+}
+
+func (query HistoryQuery) matches(receipt Receipt) bool {
+	if query.FriendID != "" && !receipt.concerns(query.FriendID) {
+		return false
+	}
+
+	if query.MessageID != "" && receipt.MessageID != query.MessageID {
+		return false
+	}
+
+	if !query.From.IsZero() && receipt.SentAt.Before(query.From) {
+		return false
+	}
+
+	if !query.To.IsZero() && !receipt.SentAt.Before(query.To) {
+		return false
+	}
+
+	return query.Status == "" || receipt.Status == query.Status
+}
+
+// filterReceipts returns the receipts matching query, most recent first.
+func filterReceipts(receipts []Receipt, query HistoryQuery) []Receipt {
+	matches := []Receipt{}
+	for _, receipt := range receipts {
+		if query.matches(receipt) {
+			matches = append(matches, receipt)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].SentAt.After(matches[j].SentAt)
+	})
+
+	return matches
+}
+
+type MemoryHistoryStore struct {
+	mu       sync.RWMutex
+	receipts []Receipt
+}
+
+func NewMemoryHistoryStore() *MemoryHistoryStore {
+	return &MemoryHistoryStore{}
+}
+
+func (store *MemoryHistoryStore) Record(receipt Receipt) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.receipts = append(store.receipts, receipt)
+	return nil
+}
+
+func (store *MemoryHistoryStore) Query(query HistoryQuery) ([]Receipt, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return filterReceipts(store.receipts, query), nil
+}
+
+// Erase removes every receipt about a friend, digests naming the friend
+// included, and returns the removed receipts.
+func (store *MemoryHistoryStore) Erase(friendID string) ([]Receipt, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var erased []Receipt
+	store.receipts, erased = partitionReceipts(store.receipts, friendID)
+	return erased, nil
+}
+
+// FileHistoryStore appends one JSON encoded receipt per line.
+type FileHistoryStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileHistoryStore(path string) *FileHistoryStore {
+	return &FileHistoryStore{path: path}
+}
+
+func (store *FileHistoryStore) Record(receipt Receipt) error {
+	line, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	file, err := os.OpenFile(store.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (store *FileHistoryStore) Query(query HistoryQuery) ([]Receipt, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	receipts, err := store.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return filterReceipts(receipts, query), nil
+}
+
+func (store *FileHistoryStore) read() ([]Receipt, error) {
+	file, err := os.Open(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	receipts := []Receipt{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var receipt Receipt
+		if err := json.Unmarshal(scanner.Bytes(), &receipt); err != nil {
+			return nil, errors.New("invalid history record")
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, scanner.Err()
+}
+
+// Erase rewrites the history without the receipts about a friend, digests
+// naming the friend included, and returns the removed receipts.
+func (store *FileHistoryStore) Erase(friendID string) ([]Receipt, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	receipts, err := store.read()
+	if err != nil {
+		return nil, err
+	}
+
+	kept, erased := partitionReceipts(receipts, friendID)
+	if len(erased) == 0 {
+		return erased, nil
+	}
+
+	var builder strings.Builder
+	for _, receipt := range kept {
+		line, err := json.Marshal(receipt)
+		if err != nil {
+			return nil, err
+		}
+		builder.Write(append(line, '\n'))
+	}
+
+	return erased, writeFileAtomically(store.path, []byte(builder.String()))
+}
+
+func partitionReceipts(receipts []Receipt, friendID string) ([]Receipt, []Receipt) {
+	kept, erased := []Receipt{}, []Receipt{}
+	for _, receipt := range receipts {
+		if receipt.concerns(friendID) {
+			erased = append(erased, receipt)
+		} else {
+			kept = append(kept, receipt)
+		}
+	}
+
+	return kept, erased
+}
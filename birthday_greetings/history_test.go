@@ -0,0 +1,87 @@
+package birthday_greetings
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func historyReceipts() []Receipt {
+	return []Receipt{
+		{MessageID: "1@test", FriendID: "mary", Recipient: "mary.ann@foobar.com", Status: Sent, SentAt: time.Date(2023, 9, 11, 9, 0, 0, 0, time.UTC)},
+		{MessageID: "2@test", FriendID: "john", Recipient: "john.doe@foobar.com", Status: Failed, SentAt: time.Date(2023, 10, 8, 9, 0, 0, 0, time.UTC)},
+		{MessageID: "3@test", FriendID: "mary", Recipient: "mary.ann@foobar.com", Status: Sent, SentAt: time.Date(2024, 9, 11, 9, 0, 0, 0, time.UTC)},
+	}
+}
+
+func TestMemoryHistoryStoreQueriesByFriendAndDateRange(t *testing.T) {
+	store := NewMemoryHistoryStore()
+	for _, receipt := range historyReceipts() {
+		store.Record(receipt)
+	}
+
+	receipts, err := store.Query(HistoryQuery{
+		FriendID: "mary",
+		From:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(receipts) != 1 || receipts[0].MessageID != "1@test" {
+		t.Errorf("Expected Mary's 2023 greeting but got %v", receipts)
+	}
+}
+
+func TestFileHistoryStoreQueriesByStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	for _, receipt := range historyReceipts() {
+		if err := NewFileHistoryStore(path).Record(receipt); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	receipts, err := NewFileHistoryStore(path).Query(HistoryQuery{Status: Sent})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(receipts) != 2 || receipts[0].MessageID != "3@test" || receipts[1].MessageID != "1@test" {
+		t.Errorf("Expected sent receipts, most recent first, but got %v", receipts)
+	}
+}
+
+func TestFileHistoryStoreWithoutFile(t *testing.T) {
+	receipts, err := NewFileHistoryStore(filepath.Join(t.TempDir(), "history.jsonl")).Query(HistoryQuery{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(receipts) != 0 {
+		t.Errorf("Expected no receipts but got %d", len(receipts))
+	}
+}
+
+func TestHistoryStoresEraseFriendReceipts(t *testing.T) {
+	stores := []ErasableHistoryStore{NewMemoryHistoryStore(), NewFileHistoryStore(filepath.Join(t.TempDir(), "history.jsonl"))}
+
+	for _, store := range stores {
+		for _, receipt := range historyReceipts() {
+			store.Record(receipt)
+		}
+
+		erased, err := store.Erase("mary")
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		if len(erased) != 2 {
+			t.Errorf("Expected Mary's 2 receipts to be erased but got %v", erased)
+		}
+
+		if remaining, _ := store.Query(HistoryQuery{}); len(remaining) != 1 || remaining[0].FriendID != "john" {
+			t.Errorf("Expected only John's receipt to remain but got %v", remaining)
+		}
+	}
+}
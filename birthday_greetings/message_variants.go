@@ -0,0 +1,80 @@
+package birthday_greetings
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// variantEpoch is the first year of every friend's chain of variant picks.
+// Each year's pick depends on the year before, so all picks are computed
+// from this year on.
+const variantEpoch = 1900
+
+// MessageVariant is one wording of the birthday greeting. Variants are picked
+// at random in proportion to their weight, so a variant weighing 0 is never
+// picked. An empty title or template falls back to the default one.
+type MessageVariant struct {
+	Weight   int
+	Title    string
+	Template string
+}
+
+// pickVariant returns the index of the variant friendID gets in year, or -1
+// when every weight is 0. The pick is seeded by the friend and the year, so
+// it is reproducible, and it never repeats the previous year's pick when
+// another variant can be picked.
+func pickVariant(variants []MessageVariant, friendID string, year int) int {
+	previous := -1
+	picked := -1
+
+	for current := min(variantEpoch, year); current <= year; current++ {
+		picked = weightedPick(variants, friendID, current, previous)
+		previous = picked
+	}
+
+	return picked
+}
+
+func weightedPick(variants []MessageVariant, friendID string, year int, excluded int) int {
+	total := 0
+	for i, variant := range variants {
+		if i != excluded {
+			total += variant.Weight
+		}
+	}
+
+	if total == 0 {
+		if excluded >= 0 && variants[excluded].Weight > 0 {
+			return excluded
+		}
+		return -1
+	}
+
+	sum := sha256.Sum256([]byte(friendID + "/" + strconv.Itoa(year)))
+	target := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+
+	for i, variant := range variants {
+		if i == excluded || variant.Weight == 0 {
+			continue
+		}
+
+		if target < variant.Weight {
+			return i
+		}
+		target -= variant.Weight
+	}
+
+	return -1
+}
+
+func checkVariants(variants []MessageVariant) error {
+	for i, variant := range variants {
+		if variant.Weight < 0 {
+			return fmt.Errorf("variant %d has a negative weight", i)
+		}
+	}
+
+	return nil
+}
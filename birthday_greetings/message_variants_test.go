@@ -0,0 +1,134 @@
+package birthday_greetings
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func messageVariants() []MessageVariant {
+	return []MessageVariant{
+		{Weight: 3, Template: "Happy birthday, dear {{.FirstName}}!"},
+		{Weight: 1, Template: "Many happy returns, {{.FirstName}}!"},
+		{Weight: 1, Title: "Cheers!", Template: "Cheers to another year, {{.FirstName}}!"},
+	}
+}
+
+func TestPickVariantIsReproducible(t *testing.T) {
+	for year := 2020; year < 2030; year++ {
+		if pickVariant(messageVariants(), "c4f77c3c468f1411", year) != pickVariant(messageVariants(), "c4f77c3c468f1411", year) {
+			t.Errorf("Expected the same pick for %d on every run", year)
+		}
+	}
+}
+
+func TestPickVariantNeverRepeatsPreviousYear(t *testing.T) {
+	for _, friendID := range []string{"c4f77c3c468f1411", "1ce90aa149d9fed9", "friend-1", "friend-2"} {
+		previous := pickVariant(messageVariants(), friendID, 1999)
+		for year := 2000; year < 2100; year++ {
+			picked := pickVariant(messageVariants(), friendID, year)
+			if picked == previous {
+				t.Errorf("Expected %s to get another variant in %d than in %d", friendID, year, year-1)
+			}
+			previous = picked
+		}
+	}
+}
+
+func TestPickVariantFollowsWeights(t *testing.T) {
+	counts := make([]int, 3)
+	variants := []MessageVariant{{Weight: 8}, {Weight: 1}, {Weight: 1}}
+
+	for i := 0; i < 3000; i++ {
+		counts[weightedPick(variants, "friend-"+strconv.Itoa(i), 2024, -1)]++
+	}
+
+	if counts[0] < 2100 || counts[0] > 2700 {
+		t.Errorf("Expected about 80%% of picks for the heaviest variant but got %v", counts)
+	}
+}
+
+func TestPickVariantWithSingleVariant(t *testing.T) {
+	if pickVariant([]MessageVariant{{Weight: 1}}, "friend-1", 2024) != 0 {
+		t.Errorf("Expected the only variant to be picked every year")
+	}
+}
+
+func TestPickVariantNeverPicksZeroWeights(t *testing.T) {
+	variants := []MessageVariant{{Weight: 0}, {Weight: 2}, {Weight: 0}}
+
+	for year := 2000; year < 2030; year++ {
+		if picked := pickVariant(variants, "friend-1", year); picked != 1 {
+			t.Errorf("Expected the only weighted variant in %d but got %d", year, picked)
+		}
+	}
+
+	if picked := pickVariant([]MessageVariant{{Weight: 0}}, "friend-1", 2024); picked != -1 {
+		t.Errorf("Expected no variant to be picked but got %d", picked)
+	}
+}
+
+func TestBuildBirthdayMessageOnRejectsNegativeWeights(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	options := MessageOptions{Variants: []MessageVariant{{Weight: 1}, {Weight: -1}}}
+
+	if _, err := friend.BuildBirthdayMessageOn(time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC), options); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestBuildBirthdayMessageUsesVariants(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	variants := []MessageVariant{{Weight: 0, Template: "Disabled"}, {Weight: 1, Title: "Cheers!", Template: "Cheers, {{.FirstName}}!"}}
+
+	greetings, err := friend.BuildBirthdayMessage(variants...)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	scheduled, _ := friend.BuildBirthdayMessageOn(time.Now(), MessageOptions{Variants: variants})
+	if greetings.Title() != "Cheers!" || greetings.Message() != "Cheers, John!" || !reflect.DeepEqual(greetings, scheduled) {
+		t.Errorf("Expected the scheduler's variant but got %v", greetings)
+	}
+}
+
+func TestBuildBirthdayMessageOnUsesVariants(t *testing.T) {
+	friend := Friend{ID: "c4f77c3c468f1411", FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	options := MessageOptions{Variants: messageVariants()}
+
+	seen := map[string]bool{}
+	previous := ""
+	for year := 2020; year < 2030; year++ {
+		greetings, err := friend.BuildBirthdayMessageOn(time.Date(year, 10, 8, 0, 0, 0, 0, time.UTC), options)
+		if err != nil {
+			t.Fatalf("Expected no error but got '%v'", err.Error())
+		}
+
+		if greetings.Message() == previous {
+			t.Errorf("Expected a new wording in %d but got %q again", year, previous)
+		}
+
+		if greetings.Title() != "Happy Birthday" && greetings.Title() != "Cheers!" {
+			t.Errorf("Expected the variant or default title but got %q", greetings.Title())
+		}
+
+		seen[greetings.Message()] = true
+		previous = greetings.Message()
+	}
+
+	if len(seen) < 2 {
+		t.Errorf("Expected several wordings over ten years but got %v", seen)
+	}
+}
+
+func TestMilestonesTakePrecedenceOverVariants(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1974/10/08", Email: "john.doe@foobar.com"}
+	options := MessageOptions{Variants: messageVariants(), Milestones: []Milestone{{Ages: []int{50}, Template: "Fifty years, {{.FirstName}}!"}}}
+
+	greetings, _ := friend.BuildBirthdayMessageOn(time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC), options)
+
+	if greetings.Message() != "Fifty years, John!" {
+		t.Errorf("Expected the milestone message but got %q", greetings.Message())
+	}
+}
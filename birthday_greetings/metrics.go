@@ -0,0 +1,118 @@
+package birthday_greetings
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics counts what the greeting pipeline does and exposes the counters in
+// the Prometheus text format. A nil *Metrics records nothing.
+type Metrics struct {
+	mu             sync.Mutex
+	friendsLoaded  uint64
+	greetingsBuilt uint64
+	sent           uint64
+	failed         uint64
+	retried        uint64
+	latencyBuckets []float64
+	latencyCounts  []uint64
+	latencySum     float64
+	latencyCount   uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		latencyBuckets: defaultLatencyBuckets,
+		latencyCounts:  make([]uint64, len(defaultLatencyBuckets)),
+	}
+}
+
+func (metrics *Metrics) addFriendsLoaded(n int) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.friendsLoaded += uint64(n)
+}
+
+func (metrics *Metrics) addGreetingBuilt() {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.greetingsBuilt++
+}
+
+func (metrics *Metrics) addRetried() {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	metrics.retried++
+}
+
+// observeSend records the outcome and latency of one send.
+func (metrics *Metrics) observeSend(latency time.Duration, err error) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	if err != nil {
+		metrics.failed++
+	} else {
+		metrics.sent++
+	}
+
+	seconds := latency.Seconds()
+	for i, bound := range metrics.latencyBuckets {
+		if seconds <= bound {
+			metrics.latencyCounts[i]++
+		}
+	}
+	metrics.latencySum += seconds
+	metrics.latencyCount++
+}
+
+func (metrics *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if metrics == nil {
+		metrics = NewMetrics()
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	writeCounter(w, "birthday_friends_loaded_total", "Friends loaded from the friends repository.", metrics.friendsLoaded)
+	writeCounter(w, "birthday_greetings_built_total", "Greetings built.", metrics.greetingsBuilt)
+	writeCounter(w, "birthday_greetings_sent_total", "Greetings sent successfully.", metrics.sent)
+	writeCounter(w, "birthday_greetings_failed_total", "Greetings that failed to send.", metrics.failed)
+	writeCounter(w, "birthday_greetings_retried_total", "Failed greetings sent again.", metrics.retried)
+
+	name := "birthday_send_latency_seconds"
+	fmt.Fprintf(w, "# HELP %s Time taken to send a greeting.\n# TYPE %s histogram\n", name, name)
+	for i, bound := range metrics.latencyBuckets {
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), metrics.latencyCounts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, metrics.latencyCount)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(metrics.latencySum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, metrics.latencyCount)
+}
+
+func writeCounter(w http.ResponseWriter, name string, help string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+}
@@ -0,0 +1,132 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposePrometheusText(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.addFriendsLoaded(2)
+	metrics.addGreetingBuilt()
+	metrics.observeSend(20*time.Millisecond, nil)
+	metrics.observeSend(2*time.Second, errors.New("boom"))
+	metrics.addRetried()
+	recorder := httptest.NewRecorder()
+
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := recorder.Body.String()
+	for _, want := range []string{
+		"# TYPE birthday_friends_loaded_total counter\nbirthday_friends_loaded_total 2\n",
+		"birthday_greetings_built_total 1\n",
+		"birthday_greetings_sent_total 1\n",
+		"birthday_greetings_failed_total 1\n",
+		"birthday_greetings_retried_total 1\n",
+		"# TYPE birthday_send_latency_seconds histogram\n",
+		`birthday_send_latency_seconds_bucket{le="0.025"} 1`,
+		`birthday_send_latency_seconds_bucket{le="2.5"} 2`,
+		`birthday_send_latency_seconds_bucket{le="+Inf"} 2`,
+		"birthday_send_latency_seconds_count 2\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain '%s' but got:\n%s", want, body)
+		}
+	}
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var metrics *Metrics
+
+	metrics.addFriendsLoaded(1)
+	metrics.observeSend(time.Second, nil)
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "birthday_greetings_sent_total 0\n") {
+		t.Errorf("Expected zero metrics but got %d:\n%s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestSchedulerLogsFriendIDsAndCountsMetrics(t *testing.T) {
+	var logs bytes.Buffer
+	metrics := NewMetrics()
+	repository := TextFileFriendsRepository{path: "birthdays.txt"}
+	scheduler := NewScheduler(repository, SchedulerConfig{
+		Logger:  slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Metrics: metrics,
+	})
+
+	if err := scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !strings.Contains(logs.String(), `"msg":"greeting sent"`) || !strings.Contains(logs.String(), `"friend_id"`) {
+		t.Errorf("Expected a structured 'greeting sent' log with a friend id but got:\n%s", logs.String())
+	}
+
+	if strings.Contains(logs.String(), "john.doe@foobar.com") || strings.Contains(logs.String(), "John") {
+		t.Errorf("Expected logs not to contain personal data but got:\n%s", logs.String())
+	}
+
+	if metrics.friendsLoaded != 2 || metrics.greetingsBuilt != 1 || metrics.sent != 1 {
+		t.Errorf("Expected 2 loaded, 1 built and 1 sent but got %d, %d and %d", metrics.friendsLoaded, metrics.greetingsBuilt, metrics.sent)
+	}
+}
+
+func TestSchedulerPlanCountsNoMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	scheduler := NewScheduler(TextFileFriendsRepository{path: "birthdays.txt"}, SchedulerConfig{Metrics: metrics})
+
+	greetings, err := scheduler.Plan(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+	if err != nil || len(greetings) != 1 {
+		t.Fatalf("Expected a planned greeting but got %v, '%v'", greetings, err)
+	}
+
+	if _, err := scheduler.planOn(time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if metrics.friendsLoaded != 0 || metrics.greetingsBuilt != 0 {
+		t.Errorf("Expected dry runs to count nothing but got %d loaded and %d built", metrics.friendsLoaded, metrics.greetingsBuilt)
+	}
+}
+
+type rejectingSender struct{}
+
+func (rejectingSender) Send(greetings BirthdayGreetings) (Receipt, error) {
+	return Receipt{Channel: "smtp"}, &textproto.Error{Code: 550, Msg: "<" + greetings.recipient + "> mailbox unavailable"}
+}
+
+func TestSchedulerLogsNoPersonalDataFromErrors(t *testing.T) {
+	var logs bytes.Buffer
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	os.WriteFile(path, []byte("last_name, first_name, birth_date, email, time_zone\nDoe, John, 1982/10/08, john.doe@foobar.com, Mars/Olympus\n"), 0o600)
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	NewScheduler(TextFileFriendsRepository{path: path}, SchedulerConfig{Logger: logger}).Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+
+	repository := stubFriendsRepository{friends: []Friend{{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}}}
+	NewScheduler(repository, SchedulerConfig{Logger: logger, Sender: rejectingSender{}}).Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+
+	unreadable := stubFriendsRepository{err: errors.New("invalid record for Doe, John <john.doe@foobar.com>")}
+	NewScheduler(unreadable, SchedulerConfig{Logger: logger}).Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+
+	if !strings.Contains(logs.String(), `"error_kind":"smtp 550"`) {
+		t.Errorf("Expected the kind of the delivery error to be logged but got:\n%s", logs.String())
+	}
+
+	if strings.Contains(logs.String(), "john.doe@foobar.com") || strings.Contains(logs.String(), "John") {
+		t.Errorf("Expected logs not to contain personal data but got:\n%s", logs.String())
+	}
+}
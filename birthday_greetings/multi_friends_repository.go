@@ -0,0 +1,114 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type ConflictRule int
+
+const (
+	FirstWins ConflictRule = iota
+	LastWins
+	ErrorOnConflict
+)
+
+type MultiFriendsRepository struct {
+	sources []FriendsRepository
+	rule    ConflictRule
+}
+
+type namedRepository interface {
+	Name() string
+}
+
+func NewMultiFriendsRepository(rule ConflictRule, sources ...FriendsRepository) MultiFriendsRepository {
+	return MultiFriendsRepository{sources: sources, rule: rule}
+}
+
+func (repo MultiFriendsRepository) GetFriends() ([]Friend, error) {
+	if repo.rule < FirstWins || repo.rule > ErrorOnConflict {
+		return nil, errors.New("invalid conflict rule")
+	}
+
+	merged := []Friend{}
+	byEmail := map[string]int{}
+	byNameAndBirthDate := map[string]int{}
+
+	for i, source := range repo.sources {
+		friends, err := source.GetFriends()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, friend := range friends {
+			friend.Source = sourceName(source, i)
+
+			index, found := byEmail[emailKey(friend)]
+			if !found {
+				index, found = byNameAndBirthDate[nameAndBirthDateKey(friend)]
+			}
+
+			if !found {
+				merged = append(merged, friend)
+				index = len(merged) - 1
+			} else if !sameFriendData(merged[index], friend) {
+				switch repo.rule {
+				case LastWins:
+					merged[index] = friend
+				case ErrorOnConflict:
+					return nil, fmt.Errorf("conflicting records for friend %s in %s and %s", merged[index].identity(), merged[index].Source, friend.Source)
+				}
+			}
+
+			if key := emailKey(merged[index]); key != "" {
+				byEmail[key] = index
+			}
+			byNameAndBirthDate[nameAndBirthDateKey(merged[index])] = index
+		}
+	}
+
+	// Different friends from different sources may still share an ID, which
+	// greetings, opt-outs and the API all rely on.
+	if err := checkUniqueIDs(withIDs(merged)); err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+func (repo TextFileFriendsRepository) Name() string {
+	return repo.path
+}
+
+func sourceName(source FriendsRepository, index int) string {
+	if named, ok := source.(namedRepository); ok {
+		return named.Name()
+	}
+
+	return fmt.Sprintf("source #%d", index+1)
+}
+
+func emailKey(friend Friend) string {
+	return normalizeEmail(friend.Email)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func nameAndBirthDateKey(friend Friend) string {
+	return strings.ToLower(strings.Join([]string{
+		strings.TrimSpace(friend.FirstName),
+		strings.TrimSpace(friend.LastName),
+		strings.TrimSpace(friend.BirthDate),
+	}, "|"))
+}
+
+func sameFriendData(a Friend, b Friend) bool {
+	return a.FirstName == b.FirstName &&
+		a.LastName == b.LastName &&
+		a.BirthDate == b.BirthDate &&
+		emailKey(a) == emailKey(b)
+}
@@ -0,0 +1,121 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type stubFriendsRepository struct {
+	friends []Friend
+	err     error
+}
+
+func (repo stubFriendsRepository) GetFriends() ([]Friend, error) {
+	return repo.friends, repo.err
+}
+
+func TestGetFriendsFromMultipleSourcesDeduplicatesByEmail(t *testing.T) {
+	first := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+	}}
+	second := stubFriendsRepository{friends: []Friend{
+		{FirstName: "Johnny", LastName: "Doe", BirthDate: "1982/10/08", Email: " John.Doe@FooBar.com "},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
+	}}
+	repository := NewMultiFriendsRepository(FirstWins, first, second)
+	want := []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com", Source: "source #1"},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com", Source: "source #2"},
+	}
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(friends, want) {
+		t.Errorf("Expected %v but got %v", want, friends)
+	}
+}
+
+func TestGetFriendsFromMultipleSourcesDeduplicatesByNameAndBirthDate(t *testing.T) {
+	first := stubFriendsRepository{friends: []Friend{
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
+	}}
+	second := stubFriendsRepository{friends: []Friend{
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary@example.com"},
+	}}
+	repository := NewMultiFriendsRepository(LastWins, first, second)
+	want := []Friend{
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary@example.com", Source: "source #2"},
+	}
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(friends, want) {
+		t.Errorf("Expected %v but got %v", want, friends)
+	}
+}
+
+func TestGetFriendsFromMultipleSourcesWithConflictError(t *testing.T) {
+	first := stubFriendsRepository{friends: []Friend{
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
+	}}
+	second := stubFriendsRepository{friends: []Friend{
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/12", Email: "mary.ann@foobar.com"},
+	}}
+	repository := NewMultiFriendsRepository(ErrorOnConflict, first, second)
+
+	_, err := repository.GetFriends()
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestGetFriendsFromMultipleSourcesIgnoresIdenticalDuplicatesOnConflictError(t *testing.T) {
+	friend := Friend{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"}
+	repository := NewMultiFriendsRepository(ErrorOnConflict, TextFileFriendsRepository{path: "birthdays.txt"}, stubFriendsRepository{friends: []Friend{friend}})
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(friends) != 2 {
+		t.Errorf("Expected 2 friends but got %d", len(friends))
+	}
+
+	if friends[1].Source != "birthdays.txt" {
+		t.Errorf("Expected source to be 'birthdays.txt' but got '%s'", friends[1].Source)
+	}
+}
+
+func TestGetFriendsFromMultipleSourcesRejectsDuplicateIDs(t *testing.T) {
+	first := stubFriendsRepository{friends: []Friend{
+		{ID: "friend-1", FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+	}}
+	second := stubFriendsRepository{friends: []Friend{
+		{ID: "friend-1", FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
+	}}
+
+	_, err := NewMultiFriendsRepository(FirstWins, first, second).GetFriends()
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestGetFriendsFromMultipleSourcesWithFailingSource(t *testing.T) {
+	repository := NewMultiFriendsRepository(FirstWins, stubFriendsRepository{err: errors.New("boom")})
+
+	_, err := repository.GetFriends()
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
@@ -0,0 +1,153 @@
+package birthday_greetings
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// OptOutStore records the friends who asked not to receive greetings,
+// keyed by friend ID.
+type OptOutStore interface {
+	IsOptedOut(id string) (bool, error)
+	OptOut(id string) error
+}
+
+type MemoryOptOutStore struct {
+	mu  sync.RWMutex
+	ids map[string]bool
+}
+
+func NewMemoryOptOutStore() *MemoryOptOutStore {
+	return &MemoryOptOutStore{ids: map[string]bool{}}
+}
+
+func (store *MemoryOptOutStore) IsOptedOut(id string) (bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.ids[strings.TrimSpace(id)], nil
+}
+
+func (store *MemoryOptOutStore) OptOut(id string) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("friend id is empty")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.ids[strings.TrimSpace(id)] = true
+	return nil
+}
+
+// Remove forgets an opt-out and reports whether there was one.
+func (store *MemoryOptOutStore) Remove(id string) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	found := store.ids[strings.TrimSpace(id)]
+	delete(store.ids, strings.TrimSpace(id))
+	return found, nil
+}
+
+// FileOptOutStore keeps one opted-out friend ID per line.
+type FileOptOutStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileOptOutStore(path string) *FileOptOutStore {
+	return &FileOptOutStore{path: path}
+}
+
+func (store *FileOptOutStore) IsOptedOut(id string) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ids, err := store.read()
+	if err != nil {
+		return false, err
+	}
+
+	return ids[strings.TrimSpace(id)], nil
+}
+
+func (store *FileOptOutStore) OptOut(id string) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("friend id is empty")
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ids, err := store.read()
+	if err != nil {
+		return err
+	}
+
+	if ids[strings.TrimSpace(id)] {
+		return nil
+	}
+
+	file, err := os.OpenFile(store.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(strings.TrimSpace(id) + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Remove rewrites the file without id and reports whether it was there.
+func (store *FileOptOutStore) Remove(id string) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ids, err := store.read()
+	if err != nil || !ids[strings.TrimSpace(id)] {
+		return false, err
+	}
+
+	data, err := os.ReadFile(store.path)
+	if err != nil {
+		return false, err
+	}
+
+	var builder strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && line != strings.TrimSpace(id) {
+			builder.WriteString(line + "\n")
+		}
+	}
+
+	return true, writeFileAtomically(store.path, []byte(builder.String()))
+}
+
+func (store *FileOptOutStore) read() (map[string]bool, error) {
+	ids := map[string]bool{}
+
+	file, err := os.Open(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ids, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			ids[id] = true
+		}
+	}
+
+	return ids, scanner.Err()
+}
@@ -0,0 +1,68 @@
+package birthday_greetings
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryOptOutStoreRecordsOptOuts(t *testing.T) {
+	store := NewMemoryOptOutStore()
+
+	if err := store.OptOut("c4f77c3c468f1411"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	optedOut, err := store.IsOptedOut("c4f77c3c468f1411")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !optedOut {
+		t.Errorf("Expected c4f77c3c468f1411 to be opted out")
+	}
+}
+
+func TestFileOptOutStorePersistsOptOuts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opt_outs.txt")
+
+	if err := NewFileOptOutStore(path).OptOut("1ce90aa149d9fed9"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	store := NewFileOptOutStore(path)
+	maryOptedOut, _ := store.IsOptedOut("1ce90aa149d9fed9")
+	johnOptedOut, _ := store.IsOptedOut("c4f77c3c468f1411")
+
+	if !maryOptedOut || johnOptedOut {
+		t.Errorf("Expected only Mary to be opted out")
+	}
+}
+
+func TestOptOutWithoutFriendID(t *testing.T) {
+	err := NewMemoryOptOutStore().OptOut(" ")
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestOptOutStoresRemoveOptOuts(t *testing.T) {
+	stores := []ErasableOptOutStore{NewMemoryOptOutStore(), NewFileOptOutStore(filepath.Join(t.TempDir(), "opt_outs.txt"))}
+
+	for _, store := range stores {
+		store.OptOut("c4f77c3c468f1411")
+		store.OptOut("1ce90aa149d9fed9")
+
+		if removed, err := store.Remove("c4f77c3c468f1411"); err != nil || !removed {
+			t.Errorf("Expected the opt-out to be removed but got %v, %v", removed, err)
+		}
+
+		if removed, _ := store.Remove("c4f77c3c468f1411"); removed {
+			t.Errorf("Expected a second removal to find nothing")
+		}
+
+		if optedOut, _ := store.IsOptedOut("1ce90aa149d9fed9"); !optedOut {
+			t.Errorf("Expected other opt-outs to be kept")
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// PersonalData gathers every store holding data about friends, to answer
+// data subject requests. Stores left nil are skipped.
+type PersonalData struct {
+	Friends  FriendsStore
+	History  ErasableHistoryStore
+	OptOuts  ErasableOptOutStore
+	Archives []PersonalDataArchive
+}
+
+type ErasableHistoryStore interface {
+	HistoryStore
+	Erase(friendID string) ([]Receipt, error)
+}
+
+type ErasableOptOutStore interface {
+	OptOutStore
+	Remove(id string) (bool, error)
+}
+
+// PersonalDataArchive is an archive of sent messages, such as an mbox file
+// or a Maildir, searched by recipient address and message ID.
+type PersonalDataArchive interface {
+	Find(selection ArchiveSelection) ([]ArchivedMessage, error)
+	Erase(selection ArchiveSelection) ([]ArchivedMessage, error)
+}
+
+// PersonalDataReport is the machine readable answer to an export or erasure
+// request. An erasure report lists what was removed without repeating the
+// removed data.
+type PersonalDataReport struct {
+	FriendID    string            `json:"friend_id"`
+	Action      string            `json:"action"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	FriendFound bool              `json:"friend_found"`
+	Friend      *Friend           `json:"friend,omitempty"`
+	Receipts    []Receipt         `json:"receipts,omitempty"`
+	MessageIDs  []string          `json:"message_ids"`
+	OptedOut    bool              `json:"opted_out"`
+	Archives    []ArchivedMessage `json:"archives"`
+}
+
+// Export collects everything held about the friend with friendID.
+func (data PersonalData) Export(friendID string) (PersonalDataReport, error) {
+	report, friend, err := data.lookup(friendID, "export")
+	if err != nil {
+		return report, err
+	}
+
+	if report.FriendFound {
+		report.Friend = &friend
+	}
+
+	if data.History != nil {
+		receipts, err := data.History.Query(HistoryQuery{FriendID: friendID})
+		if err != nil {
+			return report, err
+		}
+		report.Receipts = receipts
+		report.MessageIDs = messageIDs(receipts)
+	}
+
+	for _, key := range optOutKeys(report.FriendID, friend) {
+		if data.OptOuts == nil || report.OptedOut {
+			break
+		}
+
+		if report.OptedOut, err = data.OptOuts.IsOptedOut(key); err != nil {
+			return report, err
+		}
+	}
+
+	selection := data.archiveSelection(friend, report.Receipts)
+	for _, archive := range data.Archives {
+		found, err := archive.Find(selection)
+		if err != nil {
+			return report, err
+		}
+		report.Archives = append(report.Archives, found...)
+	}
+
+	return report, nil
+}
+
+// Erase removes the friend with friendID from every store. It goes on after
+// a failing store so that as much as possible is erased, and reports what was
+// removed along with the joined errors.
+func (data PersonalData) Erase(friendID string) (PersonalDataReport, error) {
+	report, friend, err := data.lookup(friendID, "erase")
+	if err != nil {
+		return report, err
+	}
+
+	errs := []error{}
+
+	var receipts []Receipt
+	if data.History != nil {
+		receipts, err = data.History.Erase(friendID)
+		errs = append(errs, err)
+		report.MessageIDs = messageIDs(receipts)
+	}
+
+	selection := data.archiveSelection(friend, receipts)
+
+	if report.FriendFound {
+		errs = append(errs, data.removeFriend(friendID))
+	}
+
+	for _, key := range optOutKeys(report.FriendID, friend) {
+		if data.OptOuts == nil {
+			break
+		}
+
+		removed, err := data.OptOuts.Remove(key)
+		report.OptedOut = report.OptedOut || removed
+		errs = append(errs, err)
+	}
+
+	for _, archive := range data.Archives {
+		erased, err := archive.Erase(selection)
+		errs = append(errs, err)
+		for _, message := range erased {
+			report.Archives = append(report.Archives, ArchivedMessage{Archive: message.Archive, MessageID: message.MessageID})
+		}
+	}
+
+	return report, errors.Join(errs...)
+}
+
+func (data PersonalData) lookup(friendID string, action string) (PersonalDataReport, Friend, error) {
+	report := PersonalDataReport{
+		FriendID:    strings.TrimSpace(friendID),
+		Action:      action,
+		GeneratedAt: time.Now().UTC(),
+		MessageIDs:  []string{},
+		Archives:    []ArchivedMessage{},
+	}
+
+	if report.FriendID == "" {
+		return report, Friend{}, errors.New("friend id is empty")
+	}
+
+	if data.Friends == nil {
+		return report, Friend{}, nil
+	}
+
+	friends, err := data.Friends.GetFriends()
+	if err != nil {
+		return report, Friend{}, err
+	}
+
+	index := findFriend(friends, report.FriendID)
+	if index < 0 {
+		return report, Friend{}, nil
+	}
+
+	report.FriendFound = true
+	return report, friends[index], nil
+}
+
+func (data PersonalData) removeFriend(friendID string) error {
+	friends, err := data.Friends.GetFriends()
+	if err != nil {
+		return err
+	}
+
+	if index := findFriend(friends, friendID); index >= 0 {
+		return data.Friends.SaveFriends(append(friends[:index], friends[index+1:]...))
+	}
+
+	return nil
+}
+
+// optOutKeys are the keys the friend's opt-out may be recorded under: the
+// friend ID and, for opt-outs recorded before friends had IDs, the address.
+func optOutKeys(friendID string, friend Friend) []string {
+	if email := normalizeEmail(friend.Email); email != "" {
+		return []string{friendID, email}
+	}
+
+	return []string{friendID}
+}
+
+// archiveSelection selects the messages sent to the addresses the friend
+// was greeted at, the current one and those recorded in receipts, which may
+// predate an address change, and the other messages about the friend by
+// message ID. Gift reminders about the friend went to other people, so only
+// their message IDs are selected.
+func (data PersonalData) archiveSelection(friend Friend, receipts []Receipt) ArchiveSelection {
+	seen := map[string]bool{}
+	selection := ArchiveSelection{MessageIDs: messageIDs(receipts)}
+
+	for _, address := range append([]string{friend.Email}, greetedAddresses(receipts)...) {
+		if key := normalizeEmail(address); key != "" && !seen[key] {
+			seen[key] = true
+			selection.Recipients = append(selection.Recipients, address)
+		}
+	}
+
+	return selection
+}
+
+func greetedAddresses(receipts []Receipt) []string {
+	addresses := []string{}
+	for _, receipt := range receipts {
+		if receipt.Kind == "" {
+			addresses = append(addresses, receipt.Recipient)
+		}
+	}
+
+	return addresses
+}
+
+func messageIDs(receipts []Receipt) []string {
+	ids := make([]string, len(receipts))
+	for i, receipt := range receipts {
+		ids[i] = receipt.MessageID
+	}
+
+	return ids
+}
@@ -0,0 +1,188 @@
+package birthday_greetings
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const johnID = "c4f77c3c468f1411"
+
+func newPersonalDataFixture(t *testing.T) (PersonalData, string) {
+	dir := t.TempDir()
+	friendsPath := filepath.Join(dir, "birthdays.txt")
+	data, _ := os.ReadFile("birthdays.txt")
+	os.WriteFile(friendsPath, data, 0o600)
+
+	mbox := NewMboxSender(filepath.Join(dir, "greetings.mbox"), "greetings@example.com")
+	maildir := NewMaildirSender(filepath.Join(dir, "Maildir"), "greetings@example.com")
+	history := NewFileHistoryStore(filepath.Join(dir, "history.jsonl"))
+	optOuts := NewFileOptOutStore(filepath.Join(dir, "opt_outs.txt"))
+
+	repository := TextFileFriendsRepository{path: friendsPath}
+	scheduler := NewScheduler(repository, SchedulerConfig{Sender: NewTeeSender(NoopSender{}, mbox, maildir), History: history})
+	scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+	scheduler.Run(time.Date(2024, 9, 11, 9, 0, 0, 0, time.UTC))
+	optOuts.OptOut(johnID)
+
+	return PersonalData{Friends: repository, History: history, OptOuts: optOuts, Archives: []PersonalDataArchive{mbox, maildir}}, dir
+}
+
+func TestPersonalDataExportCollectsEverything(t *testing.T) {
+	data, _ := newPersonalDataFixture(t)
+
+	report, err := data.Export(johnID)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !report.FriendFound || report.Friend.Email != "john.doe@foobar.com" || report.Action != "export" {
+		t.Errorf("Expected John's record in the report but got %v", report)
+	}
+
+	if len(report.Receipts) != 1 || len(report.MessageIDs) != 1 || !report.OptedOut {
+		t.Errorf("Expected John's receipt and opt-out but got %v", report)
+	}
+
+	if len(report.Archives) != 2 || !strings.Contains(report.Archives[0].Content, "Happy birthday, dear John Doe!") {
+		t.Errorf("Expected John's message in both archives but got %v", report.Archives)
+	}
+
+	if report.Archives[0].MessageID != report.MessageIDs[0] {
+		t.Errorf("Expected archived message %s but got %s", report.MessageIDs[0], report.Archives[0].MessageID)
+	}
+
+	encoded, err := json.Marshal(report)
+	if err != nil || !strings.Contains(string(encoded), `"friend_id":"c4f77c3c468f1411"`) {
+		t.Errorf("Expected a JSON report but got %s, '%v'", encoded, err)
+	}
+}
+
+func TestPersonalDataEraseRemovesEverything(t *testing.T) {
+	data, _ := newPersonalDataFixture(t)
+
+	report, err := data.Erase(johnID)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if !report.FriendFound || report.Friend != nil || len(report.MessageIDs) != 1 || !report.OptedOut || len(report.Archives) != 2 {
+		t.Errorf("Expected a report of every removal but got %v", report)
+	}
+
+	for _, archived := range report.Archives {
+		if archived.Content != "" {
+			t.Errorf("Expected the erasure report not to repeat the message but got %v", archived)
+		}
+	}
+
+	after, err := data.Export(johnID)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if after.FriendFound || len(after.Receipts) != 0 || after.OptedOut || len(after.Archives) != 0 {
+		t.Errorf("Expected nothing left about John but got %v", after)
+	}
+
+	mary, _ := data.Export("1ce90aa149d9fed9")
+	if !mary.FriendFound || len(mary.Receipts) != 1 || len(mary.Archives) != 2 {
+		t.Errorf("Expected Mary's data to be kept but got %v", mary)
+	}
+}
+
+func TestPersonalDataErasesAfterFriendWasDeleted(t *testing.T) {
+	data, _ := newPersonalDataFixture(t)
+	friends, _ := data.Friends.GetFriends()
+	data.Friends.SaveFriends(friends[1:])
+
+	report, err := data.Erase(johnID)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if report.FriendFound || len(report.Archives) != 2 {
+		t.Errorf("Expected archives to be found from the receipts but got %v", report)
+	}
+}
+
+func TestPersonalDataRejectsEmptyID(t *testing.T) {
+	if _, err := (PersonalData{}).Export(" "); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestPersonalDataEraseKeepsTheOrganizersMail(t *testing.T) {
+	dir := t.TempDir()
+	friendsPath := filepath.Join(dir, "birthdays.txt")
+	data, _ := os.ReadFile("birthdays.txt")
+	os.WriteFile(friendsPath, data, 0o600)
+
+	mbox := NewMboxSender(filepath.Join(dir, "greetings.mbox"), "greetings@example.com")
+	history := NewFileHistoryStore(filepath.Join(dir, "history.jsonl"))
+	repository := TextFileFriendsRepository{path: friendsPath}
+	scheduler := NewScheduler(repository, SchedulerConfig{
+		Sender:        NewTeeSender(NoopSender{}, mbox),
+		History:       history,
+		GiftReminders: GiftReminderConfig{LeadDays: 7, Recipients: []string{"john.doe@foobar.com"}},
+	})
+	for _, day := range []time.Time{time.Date(2024, 9, 4, 9, 0, 0, 0, time.UTC), time.Date(2024, 9, 11, 9, 0, 0, 0, time.UTC), time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)} {
+		scheduler.Run(day)
+	}
+	personalData := PersonalData{Friends: repository, History: history, Archives: []PersonalDataArchive{mbox}}
+
+	report, err := personalData.Erase("1ce90aa149d9fed9")
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if len(report.Archives) != 2 {
+		t.Errorf("Expected Mary's greeting and the reminder about her to be erased but got %v", report.Archives)
+	}
+
+	john, _ := mbox.Find(ArchiveSelection{Recipients: []string{"john.doe@foobar.com"}})
+	if len(john) != 1 || !strings.Contains(john[0].Content, "Happy birthday, dear John Doe!") {
+		t.Errorf("Expected John's own greeting to be kept but got %v", john)
+	}
+}
+
+func TestPersonalDataEraseRemovesDigestsNamingTheFriend(t *testing.T) {
+	dir := t.TempDir()
+	friendsPath := filepath.Join(dir, "birthdays.txt")
+	data, _ := os.ReadFile("birthdays.txt")
+	os.WriteFile(friendsPath, data, 0o600)
+
+	mbox := NewMboxSender(filepath.Join(dir, "greetings.mbox"), "greetings@example.com")
+	history := NewFileHistoryStore(filepath.Join(dir, "history.jsonl"))
+	repository := TextFileFriendsRepository{path: friendsPath}
+	scheduler := NewScheduler(repository, SchedulerConfig{
+		Sender:  NewTeeSender(NoopSender{}, mbox),
+		History: history,
+		Digest:  DigestConfig{Recipients: []string{"office@example.com"}},
+	})
+	scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+	personalData := PersonalData{Friends: repository, History: history, Archives: []PersonalDataArchive{mbox}}
+
+	export, _ := personalData.Export(johnID)
+	if len(export.Receipts) != 2 {
+		t.Errorf("Expected John's greeting and the digest naming him to be exported but got %v", export.Receipts)
+	}
+
+	if _, err := personalData.Erase(johnID); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	receipts, _ := history.Query(HistoryQuery{})
+	for _, receipt := range receipts {
+		if strings.Contains(receipt.Message, "John Doe") {
+			t.Errorf("Expected no receipt naming John to be kept but got %v", receipt)
+		}
+	}
+
+	if digests, _ := mbox.Find(ArchiveSelection{Recipients: []string{"office@example.com"}}); len(digests) != 0 {
+		t.Errorf("Expected the archived digest to be erased but got %v", digests)
+	}
+}
@@ -0,0 +1,89 @@
+package birthday_greetings
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"time"
+)
+
+type DeliveryStatus string
+
+// Held receipts record greetings waiting for the send window, until a
+// receipt of their delivery follows. Planned reports the greetings of a dry
+// run and is never recorded.
+const (
+	Sent    DeliveryStatus = "sent"
+	Failed  DeliveryStatus = "failed"
+	Held    DeliveryStatus = "held"
+	Planned DeliveryStatus = "planned"
+)
+
+// Receipt describes one attempt at delivering a greeting. It keeps the
+// greeting itself so that failed deliveries can be retried from history. A
+// greeting that was delivered but could not be archived is sent, with the
+// archive error in Error.
+// Kind is empty for greetings and names other messages, such as gift
+// reminders. A digest belongs to no single friend, so it lists the friends it
+// names in Mentions instead of FriendID.
+type Receipt struct {
+	MessageID        string            `json:"message_id"`
+	Channel          string            `json:"channel"`
+	SentAt           time.Time         `json:"sent_at"`
+	Recipient        string            `json:"recipient"`
+	FriendID         string            `json:"friend_id,omitempty"`
+	Status           DeliveryStatus    `json:"status"`
+	ReleaseAt        time.Time         `json:"release_at,omitzero"`
+	ProviderResponse string            `json:"provider_response,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	RetryOf          string            `json:"retry_of,omitempty"`
+	Title            string            `json:"title"`
+	Message          string            `json:"message"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Kind             string            `json:"kind,omitempty"`
+	Mentions         []string          `json:"mentions,omitempty"`
+
+	// message is the raw message handed to the transport, for senders that
+	// format one, so that archives can store it as sent.
+	message []byte
+}
+
+// Sender delivers greetings through a channel such as email and reports the
+// provider's answer in the receipt.
+type Sender interface {
+	Send(greetings BirthdayGreetings) (Receipt, error)
+}
+
+// NoopSender accepts every greeting without delivering it anywhere.
+type NoopSender struct{}
+
+func (NoopSender) Send(greetings BirthdayGreetings) (Receipt, error) {
+	return Receipt{Channel: "noop", ProviderResponse: "accepted"}, nil
+}
+
+func (receipt Receipt) Failed() bool {
+	return receipt.Status == Failed
+}
+
+// concerns reports whether the receipt is about the friend with friendID.
+func (receipt Receipt) concerns(friendID string) bool {
+	return receipt.FriendID == friendID || slices.Contains(receipt.Mentions, friendID)
+}
+
+func (receipt Receipt) greetings() BirthdayGreetings {
+	return BirthdayGreetings{
+		title:     receipt.Title,
+		message:   receipt.Message,
+		recipient: receipt.Recipient,
+		headers:   receipt.Headers,
+		messageID: receipt.MessageID,
+		kind:      receipt.Kind,
+		mentions:  receipt.Mentions,
+	}
+}
+
+func newMessageID() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return hex.EncodeToString(random) + "@birthday-greetings"
+}
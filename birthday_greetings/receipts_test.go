@@ -0,0 +1,30 @@
+package birthday_greetings
+
+import "testing"
+
+func TestSendBirthdayGreetingsReturnsReceipt(t *testing.T) {
+	greetings := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear Jane Doe!", recipient: "jane.smith@example.com"}
+
+	receipt, err := greetings.Send()
+	if err != nil {
+		t.Errorf("Expected no error but got '%v'", err.Error())
+	}
+
+	if receipt.MessageID == "" || receipt.Recipient != "jane.smith@example.com" || receipt.Status != Sent || receipt.SentAt.IsZero() {
+		t.Errorf("Expected a complete sent receipt but got %v", receipt)
+	}
+}
+
+func TestSendWithFailingSenderReturnsFailedReceipt(t *testing.T) {
+	greetings := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear Jane Doe!", recipient: "jane.smith@example.com"}
+
+	receipt, err := greetings.SendWith(failingSender{})
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	if receipt.Status != Failed || receipt.Error != "mailbox unavailable" || receipt.ProviderResponse != "550 mailbox unavailable" {
+		t.Errorf("Expected a failed receipt with the provider response but got %v", receipt)
+	}
+}
@@ -0,0 +1,45 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// RunMarker remembers when the scheduler last completed a run successfully.
+type RunMarker interface {
+	LastRun() (time.Time, bool, error)
+	MarkRun(at time.Time) error
+}
+
+type FileRunMarker struct {
+	path string
+}
+
+func NewFileRunMarker(path string) FileRunMarker {
+	return FileRunMarker{path: path}
+}
+
+// LastRun reports false when no run has been recorded yet.
+func (marker FileRunMarker) LastRun() (time.Time, bool, error) {
+	data, err := os.ReadFile(marker.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, false, nil
+	}
+
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	lastRun, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}, false, errors.New("invalid run marker")
+	}
+
+	return lastRun, true, nil
+}
+
+func (marker FileRunMarker) MarkRun(at time.Time) error {
+	return os.WriteFile(marker.path, []byte(at.Format(time.RFC3339)+"\n"), 0o600)
+}
@@ -0,0 +1,38 @@
+package birthday_greetings
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRunMarkerWithoutPreviousRun(t *testing.T) {
+	marker := NewFileRunMarker(filepath.Join(t.TempDir(), "last_run"))
+
+	_, hasRun, err := marker.LastRun()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if hasRun {
+		t.Errorf("Expected no previous run")
+	}
+}
+
+func TestFileRunMarkerRemembersLastRun(t *testing.T) {
+	marker := NewFileRunMarker(filepath.Join(t.TempDir(), "last_run"))
+	at := time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)
+
+	if err := marker.MarkRun(at); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	lastRun, hasRun, err := marker.LastRun()
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !hasRun || !lastRun.Equal(at) {
+		t.Errorf("Expected last run at %v but got %v", at, lastRun)
+	}
+}
@@ -0,0 +1,443 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Scheduler runs the daily birthday pipeline: it loads friends, matches
+// birthdays against each friend's local date and sends greetings inside the
+// send window. Greetings outside the window are held until it opens.
+type Scheduler struct {
+	mu     sync.Mutex
+	repo   FriendsRepository
+	config SchedulerConfig
+}
+
+type SchedulerConfig struct {
+	Window             SendWindow
+	Options            MessageOptions
+	Calendar           CalendarPolicy
+	Marker             RunMarker
+	MaxCatchUpDays     int
+	OptOuts            OptOutStore
+	Unsubscribe        *UnsubscribeSigner
+	Logger             *slog.Logger
+	Metrics            *Metrics
+	Sender             Sender
+	History            HistoryStore
+	GiftReminders      GiftReminderConfig
+	ColleagueReminders ColleagueReminderConfig
+	Digest             DigestConfig
+	Retry              RetryPolicy
+	TimeZone           *time.Location
+	Tags               TagExpression
+	Groups             []FriendGroup
+}
+
+// RetryPolicy bounds automatic retries of failed deliveries. MaxAttempts
+// counts the first attempt, so values below 2 disable retries.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// NewScheduler keeps receipts in memory unless config names a history store.
+func NewScheduler(repo FriendsRepository, config SchedulerConfig) *Scheduler {
+	if config.History == nil {
+		config.History = NewMemoryHistoryStore()
+	}
+
+	return &Scheduler{repo: repo, config: config}
+}
+
+type scheduledGreeting struct {
+	friend    Friend
+	greetings BirthdayGreetings
+	local     time.Time
+	status    DeliveryStatus
+}
+
+// Run releases held greetings that are due, then sends or holds greetings for
+// every friend event delivered on the friend's local date at now. With a
+// retry policy, it first retries failed deliveries. With a run marker,
+// birthdays missed since the last run get belated greetings, going back at
+// most MaxCatchUpDays days; other missed events are not caught up. The
+// marker advances even when single friends fail, so that the other friends'
+// greetings are not sent again.
+func (scheduler *Scheduler) Run(now time.Time) error {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if err := scheduler.retryFailures(now); err != nil {
+		scheduler.logger().Error("retrying failed greetings failed", "error_kind", errorKind(err))
+	}
+
+	errs := []error{scheduler.release(now)}
+
+	planned, err := scheduler.plan(now, planning{catchUp: true})
+	errs = append(errs, err)
+
+	for i := range planned {
+		errs = append(errs, scheduler.deliver(&planned[i], planned[i].local))
+	}
+
+	var aborted abortedRun
+	if !errors.As(err, &aborted) {
+		errs = append(errs, scheduler.sendDigest(now))
+
+		if scheduler.config.Marker != nil {
+			errs = append(errs, scheduler.config.Marker.MarkRun(now))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sendOn delivers the greetings due on date, deciding the send window as if
+// it were noon UTC that day, and stamps their receipts with sentAt. As date
+// may lie before or after today, it leaves everything else to the scheduled
+// runs: missed birthdays, retries, held greetings, the digest and the run
+// marker.
+func (scheduler *Scheduler) sendOn(date time.Time, sentAt time.Time) ([]scheduledGreeting, error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	planned, err := scheduler.plan(date.Add(12*time.Hour), planning{})
+	errs := []error{err}
+
+	for i := range planned {
+		errs = append(errs, scheduler.deliver(&planned[i], sentAt))
+	}
+
+	return planned, errors.Join(errs...)
+}
+
+// abortedRun wraps the errors that keep a run from planning anything, as
+// opposed to errors about single friends.
+type abortedRun struct{ err error }
+
+func (aborted abortedRun) Error() string { return aborted.err.Error() }
+
+func (aborted abortedRun) Unwrap() error { return aborted.err }
+
+// Plan returns the greetings a run at now would deliver, without sending,
+// holding or marking anything.
+func (scheduler *Scheduler) Plan(now time.Time) ([]BirthdayGreetings, error) {
+	return scheduler.planGreetings(now, planning{catchUp: true, dryRun: true})
+}
+
+// planOn returns the greetings sendOn would deliver for date.
+func (scheduler *Scheduler) planOn(date time.Time) ([]BirthdayGreetings, error) {
+	return scheduler.planGreetings(date.Add(12*time.Hour), planning{dryRun: true})
+}
+
+func (scheduler *Scheduler) planGreetings(now time.Time, mode planning) ([]BirthdayGreetings, error) {
+	scheduler.mu.Lock()
+	planned, err := scheduler.plan(now, mode)
+	scheduler.mu.Unlock()
+
+	greetings := make([]BirthdayGreetings, len(planned))
+	for i, scheduled := range planned {
+		greetings[i] = scheduled.greetings
+	}
+
+	return greetings, err
+}
+
+// planning says how plan works: scheduled runs add belated greetings for the
+// birthdays missed since the last run, and dry runs are left out of the
+// metrics.
+type planning struct {
+	catchUp bool
+	dryRun  bool
+}
+
+// plan builds the greetings and reminders due on each friend's local
+// date at now, skipping friends in the opt-out store before building any.
+func (scheduler *Scheduler) plan(now time.Time, mode planning) ([]scheduledGreeting, error) {
+	lastRun, hasRun, err := scheduler.lastRun()
+	if err != nil {
+		return nil, abortedRun{err}
+	}
+
+	started := time.Now()
+	friends, err := scheduler.repo.GetFriends()
+	if err != nil {
+		scheduler.logger().Error("loading friends failed", "error_kind", errorKind(err))
+		return nil, abortedRun{err}
+	}
+
+	scheduler.logger().Info("friends loaded", "count", len(friends), "duration", time.Since(started))
+	if !mode.dryRun {
+		scheduler.config.Metrics.addFriendsLoaded(len(friends))
+	}
+
+	planned := []scheduledGreeting{}
+	errs := []error{}
+	add := func(scheduled scheduledGreeting, found bool, err error) {
+		if found {
+			planned = append(planned, scheduled)
+			if !mode.dryRun {
+				scheduler.config.Metrics.addGreetingBuilt()
+			}
+		}
+		errs = append(errs, err)
+	}
+
+	for _, friend := range FilterFriends(friends, scheduler.config.Tags) {
+		if optedOut, err := scheduler.isOptedOut(friend); err != nil || optedOut {
+			errs = append(errs, err)
+			continue
+		}
+
+		local, err := scheduler.localTime(friend, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if mode.catchUp && hasRun {
+			for _, missed := range scheduler.missedDays(lastRun, local) {
+				for _, celebration := range scheduler.config.Calendar.CelebrationDates(missed) {
+					for _, event := range friend.AllEvents() {
+						if event.Type == Birthday {
+							add(scheduler.greet(friend, event, celebration, local, true))
+						}
+					}
+				}
+			}
+		}
+
+		for _, celebration := range scheduler.config.Calendar.CelebrationDates(local) {
+			for _, event := range friend.AllEvents() {
+				add(scheduler.greet(friend, event, celebration, local, false))
+			}
+		}
+
+		reminders, err := scheduler.giftReminders(friend, local)
+		planned = append(planned, reminders...)
+		errs = append(errs, err)
+	}
+
+	reminders, err := scheduler.colleagueReminders(friends, now)
+	planned = append(planned, reminders...)
+	errs = append(errs, err)
+
+	return planned, errors.Join(errs...)
+}
+
+// Preview builds the birthday greeting friend would receive on date, with
+// the templates of the friend's group.
+func (scheduler *Scheduler) Preview(friend Friend, date time.Time) (BirthdayGreetings, error) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	greetings, err := friend.BuildBirthdayMessageOn(date, scheduler.optionsFor(friend))
+	greetings.recipient = friend.Email
+	return greetings, err
+}
+
+// localTime returns now in the friend's time zone, falling back to the
+// configured default time zone for friends without one.
+func (scheduler *Scheduler) localTime(friend Friend, now time.Time) (time.Time, error) {
+	if friend.TimeZone == "" && scheduler.config.TimeZone != nil {
+		return now.In(scheduler.config.TimeZone), nil
+	}
+
+	return friend.localTime(now)
+}
+
+func (scheduler *Scheduler) lastRun() (time.Time, bool, error) {
+	if scheduler.config.Marker == nil {
+		return time.Time{}, false, nil
+	}
+
+	return scheduler.config.Marker.LastRun()
+}
+
+// missedDays returns the local days strictly between the last run and local,
+// limited to the most recent MaxCatchUpDays days.
+func (scheduler *Scheduler) missedDays(lastRun time.Time, local time.Time) []time.Time {
+	today := startOfDay(local)
+	first := startOfDay(lastRun.In(local.Location())).AddDate(0, 0, 1)
+	if earliest := today.AddDate(0, 0, -scheduler.config.MaxCatchUpDays); first.Before(earliest) {
+		first = earliest
+	}
+
+	days := []time.Time{}
+	for day := first; day.Before(today); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	return days
+}
+
+// greet builds the greeting for event when it falls on celebration. It
+// reports false when there is nothing to send.
+func (scheduler *Scheduler) greet(friend Friend, event Event, celebration time.Time, local time.Time, belated bool) (scheduledGreeting, bool, error) {
+	occurs, err := event.OccursOn(celebration)
+	if err != nil || !occurs {
+		return scheduledGreeting{}, false, err
+	}
+
+	messageID := greetingID(friend, event, celebration)
+	if sent, err := scheduler.isPlannedOrSent(messageID); err != nil || sent {
+		return scheduledGreeting{}, false, err
+	}
+
+	var greetings BirthdayGreetings
+	if event.Type == Birthday {
+		greetings, err = friend.buildBirthdayMessage(celebration, local, belated, scheduler.optionsFor(friend))
+	} else {
+		greetings, err = friend.buildEventMessage(event, celebration, local, scheduler.optionsFor(friend))
+	}
+
+	if err != nil {
+		scheduler.logger().Error("building greeting failed", "friend_id", friend.identity(), "event", event.Type, "error_kind", errorKind(err))
+		return scheduledGreeting{}, false, err
+	}
+
+	scheduler.logger().Debug("greeting built", "friend_id", friend.identity(), "event", event.Type, "belated", belated)
+
+	greetings.recipient = friend.Email
+	greetings.messageID = messageID
+	if scheduler.config.Unsubscribe != nil {
+		greetings.headers = scheduler.config.Unsubscribe.Headers(friend.identity())
+	}
+
+	return scheduledGreeting{friend: friend, greetings: greetings, local: local}, true, nil
+}
+
+// deliver sends the greeting, recording sentAt in its receipt, or holds it
+// when the friend's local time is outside the send window.
+func (scheduler *Scheduler) deliver(scheduled *scheduledGreeting, sentAt time.Time) error {
+	if !scheduler.config.Window.Contains(scheduled.local) {
+		scheduled.status = Held
+		scheduler.logger().Info("greeting held until send window opens", "friend_id", scheduled.friend.identity())
+		return scheduler.hold(scheduled.friend, scheduled.greetings, sentAt, scheduler.config.Window.NextOpening(scheduled.local))
+	}
+
+	receipt, err := scheduler.send(scheduled.friend, scheduled.greetings, sentAt, "")
+	scheduled.status = receipt.Status
+	return err
+}
+
+// Release sends every held greeting whose send window has opened by now.
+func (scheduler *Scheduler) Release(now time.Time) error {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	return scheduler.release(now)
+}
+
+func (scheduler *Scheduler) release(now time.Time) error {
+	held, err := scheduler.heldGreetings()
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, receipt := range held {
+		if now.Before(receipt.ReleaseAt) {
+			continue
+		}
+
+		friend, err := scheduler.friendFor(receipt)
+		if err != nil {
+			if !errors.Is(err, errOptedOut) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		_, err = scheduler.send(friend, receipt.greetings(), now, "")
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// hold records the greeting as held until releaseAt in the history, so that
+// it is released even when the scheduler restarts before the window opens.
+func (scheduler *Scheduler) hold(friend Friend, greetings BirthdayGreetings, at time.Time, releaseAt time.Time) error {
+	return scheduler.history().Record(Receipt{
+		MessageID: greetings.messageID,
+		SentAt:    at,
+		Recipient: greetings.recipient,
+		FriendID:  receiptFriendID(friend, greetings),
+		Status:    Held,
+		ReleaseAt: releaseAt,
+		Title:     greetings.title,
+		Message:   greetings.message,
+		Headers:   greetings.headers,
+		Kind:      greetings.kind,
+		Mentions:  greetings.mentions,
+	})
+}
+
+// heldGreetings returns the held receipts whose greeting has not been
+// delivered yet. Held greetings of friends who opted out since stay held.
+func (scheduler *Scheduler) heldGreetings() ([]Receipt, error) {
+	held, err := scheduler.history().Query(HistoryQuery{Status: Held})
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Receipt{}
+	for _, receipt := range held {
+		receipts, err := scheduler.history().Query(HistoryQuery{MessageID: receipt.MessageID})
+		if err != nil {
+			return nil, err
+		}
+
+		delivered := slices.ContainsFunc(receipts, func(other Receipt) bool { return other.Status != Held })
+		if !delivered {
+			pending = append(pending, receipt)
+		}
+	}
+
+	return pending, nil
+}
+
+// isOptedOut also honours opt-outs recorded by email address before friends
+// had IDs, recording them under the friend's ID as well.
+func (scheduler *Scheduler) isOptedOut(friend Friend) (bool, error) {
+	store := scheduler.config.OptOuts
+	if store == nil {
+		return false, nil
+	}
+
+	if optedOut, err := store.IsOptedOut(friend.identity()); err != nil || optedOut {
+		return optedOut, err
+	}
+
+	if normalizeEmail(friend.Email) == "" {
+		return false, nil
+	}
+
+	optedOut, err := store.IsOptedOut(normalizeEmail(friend.Email))
+	if err != nil || !optedOut {
+		return false, err
+	}
+
+	return true, store.OptOut(friend.identity())
+}
+
+func (scheduler *Scheduler) logger() *slog.Logger {
+	if scheduler.config.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	return scheduler.config.Logger
+}
+
+// Held counts the greetings waiting for the send window.
+func (scheduler *Scheduler) Held() int {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	held, _ := scheduler.heldGreetings()
+	return len(held)
+}
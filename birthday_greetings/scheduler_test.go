@@ -0,0 +1,203 @@
+package birthday_greetings
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedulerHoldsGreetingsOutsideSendWindow(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com", TimeZone: "Asia/Tokyo"},
+	}}
+	scheduler := NewScheduler(repository, SchedulerConfig{Window: SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour}})
+
+	// 06:00 on October 8 in Tokyo.
+	if err := scheduler.Run(time.Date(2024, 10, 7, 21, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if scheduler.Held() != 1 {
+		t.Errorf("Expected 1 held greeting but got %d", scheduler.Held())
+	}
+
+	if err := scheduler.Release(time.Date(2024, 10, 7, 22, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if scheduler.Held() != 1 {
+		t.Errorf("Expected greeting to stay held before the window opens")
+	}
+
+	// 08:00 on October 8 in Tokyo.
+	if err := scheduler.Release(time.Date(2024, 10, 7, 23, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if scheduler.Held() != 0 {
+		t.Errorf("Expected held greeting to be released but %d remain", scheduler.Held())
+	}
+}
+
+func TestSchedulerSendsInsideSendWindow(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/09/11", Email: "mary.ann@foobar.com"},
+	}}
+	scheduler := NewScheduler(repository, SchedulerConfig{Window: SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour}})
+
+	if err := scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if scheduler.Held() != 0 {
+		t.Errorf("Expected no held greetings but got %d", scheduler.Held())
+	}
+}
+
+func TestSchedulerReportsInvalidFriends(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: ""},
+	}}
+	scheduler := NewScheduler(repository, SchedulerConfig{})
+
+	err := scheduler.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC))
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestSchedulerSendsBelatedGreetingsForMissedRuns(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/06", Email: "john.doe@foobar.com"},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/03", Email: "mary.ann@foobar.com"},
+		{FirstName: "Jane", LastName: "Smith", BirthDate: "1990/10/08", Email: "jane.smith@example.com"},
+	}}
+	marker := NewFileRunMarker(filepath.Join(t.TempDir(), "last_run"))
+	if err := marker.MarkRun(time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	scheduler := NewScheduler(repository, SchedulerConfig{
+		Window:         SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour},
+		Marker:         marker,
+		MaxCatchUpDays: 3,
+	})
+	now := time.Date(2024, 10, 8, 6, 0, 0, 0, time.UTC)
+
+	if err := scheduler.Run(now); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	held, _ := scheduler.heldGreetings()
+	if len(held) != 2 {
+		t.Fatalf("Expected 2 greetings but got %d", len(held))
+	}
+
+	belated := "Happy belated birthday, dear John Doe! Sorry we missed your birthday on Sunday, October 6."
+	if held[0].Message != belated {
+		t.Errorf("Expected '%s' but got '%s'", belated, held[0].Message)
+	}
+
+	if held[1].Title != "Happy Birthday" {
+		t.Errorf("Expected a regular greeting for today but got '%s'", held[1].Title)
+	}
+
+	lastRun, _, _ := marker.LastRun()
+	if !lastRun.Equal(now) {
+		t.Errorf("Expected run marker to be updated to %v but got %v", now, lastRun)
+	}
+}
+
+func TestSchedulerUsesDefaultTimeZoneForFriendsWithoutOne(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+	}}
+	scheduler := NewScheduler(repository, SchedulerConfig{TimeZone: tokyo})
+
+	// October 8 in Tokyo, still October 7 in UTC.
+	greetings, err := scheduler.Plan(time.Date(2024, 10, 7, 21, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(greetings) != 1 {
+		t.Errorf("Expected 1 greeting but got %d", len(greetings))
+	}
+}
+
+func TestSchedulerGreetsOncePerDayAcrossRuns(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+	}}
+	scheduler := NewScheduler(repository, SchedulerConfig{Window: SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour}})
+
+	for _, hour := range []int{6, 7, 9, 10} {
+		if err := scheduler.Run(time.Date(2024, 10, 8, hour, 0, 0, 0, time.UTC)); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{Status: Sent})
+	if len(receipts) != 1 || scheduler.Held() != 0 {
+		t.Errorf("Expected a single greeting but got %d sent and %d held", len(receipts), scheduler.Held())
+	}
+}
+
+func TestSchedulerAdvancesRunMarkerPastInvalidFriends(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/06", Email: "john.doe@foobar.com"},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/03", Email: "mary.ann@foobar.com", TimeZone: "Mars/Olympus"},
+	}}
+	marker := NewFileRunMarker(filepath.Join(t.TempDir(), "last_run"))
+	marker.MarkRun(time.Date(2024, 10, 5, 9, 0, 0, 0, time.UTC))
+	scheduler := NewScheduler(repository, SchedulerConfig{Marker: marker, MaxCatchUpDays: 7})
+
+	for day := 7; day <= 10; day++ {
+		now := time.Date(2024, 10, day, 9, 0, 0, 0, time.UTC)
+		if err := scheduler.Run(now); err == nil {
+			t.Errorf("Expected Mary's time zone to be reported but was not")
+		}
+
+		if lastRun, _, _ := marker.LastRun(); !lastRun.Equal(now) {
+			t.Errorf("Expected run marker to be updated to %v but got %v", now, lastRun)
+		}
+	}
+
+	receipts, _ := scheduler.History().Query(HistoryQuery{})
+	if len(receipts) != 1 {
+		t.Errorf("Expected a single belated greeting for John but got %d", len(receipts))
+	}
+}
+
+func TestSchedulerReleasesGreetingsHeldBeforeARestart(t *testing.T) {
+	dir := t.TempDir()
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+	}}
+	config := SchedulerConfig{
+		Window:  SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour},
+		Marker:  NewFileRunMarker(filepath.Join(dir, "last_run")),
+		History: NewFileHistoryStore(filepath.Join(dir, "history.jsonl")),
+	}
+
+	if err := NewScheduler(repository, config).Run(time.Date(2024, 10, 8, 6, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restarted := NewScheduler(repository, config)
+	if restarted.Held() != 1 {
+		t.Fatalf("Expected the held greeting to survive the restart but got %d", restarted.Held())
+	}
+
+	if err := restarted.Run(time.Date(2024, 10, 8, 9, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sent, _ := restarted.History().Query(HistoryQuery{Status: Sent})
+	if len(sent) != 1 || restarted.Held() != 0 || !strings.HasPrefix(sent[0].Message, "Happy birthday") {
+		t.Errorf("Expected the held greeting to be sent once but got %v", sent)
+	}
+}
@@ -0,0 +1,79 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SendWindow is the part of a local day during which greetings may be sent,
+// expressed as offsets from midnight. The zero value is always open.
+type SendWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseSendWindow parses windows such as "08:00-20:00".
+func ParseSendWindow(value string) (SendWindow, error) {
+	bounds := strings.Split(value, "-")
+	if len(bounds) != 2 {
+		return SendWindow{}, errors.New("invalid send window")
+	}
+
+	start, err := parseTimeOfDay(bounds[0])
+	if err != nil {
+		return SendWindow{}, err
+	}
+
+	end, err := parseTimeOfDay(bounds[1])
+	if err != nil {
+		return SendWindow{}, err
+	}
+
+	if end <= start {
+		return SendWindow{}, errors.New("send window must end after it starts")
+	}
+
+	return SendWindow{Start: start, End: end}, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.New("invalid time of day")
+	}
+
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func (window SendWindow) isAlwaysOpen() bool {
+	return window.Start == 0 && window.End == 0
+}
+
+func (window SendWindow) Contains(local time.Time) bool {
+	if window.isAlwaysOpen() {
+		return true
+	}
+
+	offset := local.Sub(startOfDay(local))
+	return offset >= window.Start && offset < window.End
+}
+
+// NextOpening returns the first instant at or after local when the window is
+// open.
+func (window SendWindow) NextOpening(local time.Time) time.Time {
+	if window.Contains(local) {
+		return local
+	}
+
+	opening := startOfDay(local).Add(window.Start)
+	if local.After(opening) {
+		opening = startOfDay(local.AddDate(0, 0, 1)).Add(window.Start)
+	}
+
+	return opening
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,45 @@
+package birthday_greetings
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSendWindow(t *testing.T) {
+	window, err := ParseSendWindow("08:00-20:00")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if window.Start != 8*time.Hour || window.End != 20*time.Hour {
+		t.Errorf("Expected 08:00-20:00 but got %v-%v", window.Start, window.End)
+	}
+}
+
+func TestParseSendWindowEndingBeforeStart(t *testing.T) {
+	_, err := ParseSendWindow("20:00-08:00")
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestSendWindowNextOpening(t *testing.T) {
+	window := SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour}
+	early := time.Date(2024, 10, 8, 6, 30, 0, 0, time.UTC)
+	late := time.Date(2024, 10, 8, 21, 0, 0, 0, time.UTC)
+
+	if got := window.NextOpening(early); !got.Equal(time.Date(2024, 10, 8, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected window to open at 08:00 the same day but got %v", got)
+	}
+
+	if got := window.NextOpening(late); !got.Equal(time.Date(2024, 10, 9, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected window to open at 08:00 the next day but got %v", got)
+	}
+}
+
+func TestZeroSendWindowIsAlwaysOpen(t *testing.T) {
+	if !(SendWindow{}).Contains(time.Date(2024, 10, 8, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected zero send window to always be open")
+	}
+}
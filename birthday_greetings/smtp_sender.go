@@ -0,0 +1,90 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"mime"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SMTPSender delivers greetings as plain text emails through an SMTP relay,
+// signing them with DKIM when a signer is set.
+type SMTPSender struct {
+	addr     string
+	from     string
+	auth     smtp.Auth
+	dkim     *DKIMSigner
+	now      func() time.Time
+	sendMail func(addr string, auth smtp.Auth, from string, to []string, message []byte) error
+}
+
+func NewSMTPSender(addr string, from string, auth smtp.Auth) *SMTPSender {
+	return &SMTPSender{addr: addr, from: from, auth: auth, now: time.Now, sendMail: smtp.SendMail}
+}
+
+// WithDKIM signs every message sent from now on with signer.
+func (sender *SMTPSender) WithDKIM(signer *DKIMSigner) *SMTPSender {
+	sender.dkim = signer
+	return sender
+}
+
+func (sender *SMTPSender) Send(greetings BirthdayGreetings) (Receipt, error) {
+	receipt := Receipt{Channel: "smtp"}
+
+	message, err := sender.Message(greetings)
+	if err != nil {
+		return receipt, err
+	}
+	receipt.message = message
+
+	if err := sender.sendMail(sender.addr, sender.auth, sender.from, []string{greetings.recipient}, message); err != nil {
+		receipt.ProviderResponse = err.Error()
+		return receipt, err
+	}
+
+	receipt.ProviderResponse = "250 accepted by " + sender.addr
+	return receipt, nil
+}
+
+// Message formats greetings as a CRLF separated RFC 5322 message, signed
+// when the sender has a DKIM signer.
+func (sender *SMTPSender) Message(greetings BirthdayGreetings) ([]byte, error) {
+	message, err := formatMessage(greetings, sender.from, sender.now())
+	if err != nil || sender.dkim == nil {
+		return message, err
+	}
+
+	return sender.dkim.Sign(message)
+}
+
+func formatMessage(greetings BirthdayGreetings, from string, date time.Time) ([]byte, error) {
+	if from == "" {
+		return nil, errors.New("sender address is empty")
+	}
+
+	headers := []string{
+		"From: " + from,
+		"To: " + greetings.recipient,
+		"Subject: " + mime.QEncoding.Encode("utf-8", greetings.title),
+		"Date: " + date.Format(time.RFC1123Z),
+		"Message-ID: <" + greetings.messageID + ">",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+
+	names := make([]string, 0, len(greetings.headers))
+	for name := range greetings.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		headers = append(headers, name+": "+greetings.headers[name])
+	}
+
+	body := strings.ReplaceAll(strings.ReplaceAll(greetings.message, "\r\n", "\n"), "\n", "\r\n")
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n"), nil
+}
@@ -0,0 +1,98 @@
+package birthday_greetings
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type capturedMail struct {
+	from    string
+	to      []string
+	message []byte
+}
+
+func newTestSMTPSender(captured *capturedMail, err error) *SMTPSender {
+	sender := NewSMTPSender("smtp.example.com:587", "greetings@example.com", nil)
+	sender.now = func() time.Time { return time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC) }
+	sender.sendMail = func(addr string, auth smtp.Auth, from string, to []string, message []byte) error {
+		*captured = capturedMail{from: from, to: to, message: message}
+		return err
+	}
+	return sender
+}
+
+func TestSMTPSenderSendsFormattedMessage(t *testing.T) {
+	var captured capturedMail
+	sender := newTestSMTPSender(&captured, nil)
+	greetings := BirthdayGreetings{
+		title:     "Happy Birthday",
+		message:   "Happy birthday, dear Jane Doe!",
+		recipient: "jane.smith@example.com",
+		headers:   map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	}
+
+	receipt, err := greetings.SendWith(sender)
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if receipt.Channel != "smtp" || receipt.Status != Sent {
+		t.Errorf("Expected a sent smtp receipt but got %v", receipt)
+	}
+
+	message := string(captured.message)
+	for _, want := range []string{
+		"From: greetings@example.com\r\n",
+		"To: jane.smith@example.com\r\n",
+		"Subject: Happy Birthday\r\n",
+		"Date: Mon, 19 Oct 2026 09:00:00 +0000\r\n",
+		"Message-ID: <" + receipt.MessageID + ">\r\n",
+		"List-Unsubscribe: <https://example.com/unsubscribe>\r\n",
+		"\r\n\r\nHappy birthday, dear Jane Doe!\r\n",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Expected message to contain %q but got %v", want, message)
+		}
+	}
+
+	if captured.from != "greetings@example.com" || len(captured.to) != 1 || captured.to[0] != "jane.smith@example.com" {
+		t.Errorf("Expected the envelope to match the greeting but got %v", captured)
+	}
+}
+
+func TestSMTPSenderSignsWithDKIM(t *testing.T) {
+	var captured capturedMail
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := NewDKIMSigner(private, DKIMOptions{Domain: "example.com", Selector: "greetings"})
+	sender := newTestSMTPSender(&captured, nil).WithDKIM(signer)
+	greetings := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear Jane Doe!", recipient: "jane.smith@example.com"}
+
+	if _, err := greetings.SendWith(sender); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if err := verifyDKIM(captured.message, public); err != nil {
+		t.Errorf("Expected a valid DKIM signature but got '%v'", err.Error())
+	}
+}
+
+func TestSMTPSenderReportsRelayErrors(t *testing.T) {
+	var captured capturedMail
+	sender := newTestSMTPSender(&captured, errors.New("550 mailbox unavailable"))
+	greetings := BirthdayGreetings{title: "Happy Birthday", message: "Happy birthday, dear Jane Doe!", recipient: "jane.smith@example.com"}
+
+	receipt, err := greetings.SendWith(sender)
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+
+	if receipt.Status != Failed || receipt.ProviderResponse != "550 mailbox unavailable" {
+		t.Errorf("Expected a failed receipt with the relay response but got %v", receipt)
+	}
+}
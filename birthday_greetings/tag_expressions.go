@@ -0,0 +1,222 @@
+package birthday_greetings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TagExpression selects friends by tag, such as "work AND NOT contractors".
+// It combines tags with AND, OR, NOT and parentheses, NOT binding tightest
+// and OR loosest. Keywords and tags are case insensitive. The zero value
+// matches every friend.
+type TagExpression struct {
+	source string
+	root   tagNode
+}
+
+type tagNode interface {
+	matches(tags map[string]bool) bool
+}
+
+type tagLeaf string
+
+type tagNot struct{ operand tagNode }
+
+type tagAnd struct{ left, right tagNode }
+
+type tagOr struct{ left, right tagNode }
+
+func (leaf tagLeaf) matches(tags map[string]bool) bool { return tags[string(leaf)] }
+
+func (not tagNot) matches(tags map[string]bool) bool { return !not.operand.matches(tags) }
+
+func (and tagAnd) matches(tags map[string]bool) bool {
+	return and.left.matches(tags) && and.right.matches(tags)
+}
+
+func (or tagOr) matches(tags map[string]bool) bool {
+	return or.left.matches(tags) || or.right.matches(tags)
+}
+
+type tagParser struct {
+	tokens []string
+	next   int
+}
+
+func ParseTagExpression(source string) (TagExpression, error) {
+	tokens := tokenizeTags(source)
+	if len(tokens) == 0 {
+		return TagExpression{}, errors.New("tag expression is empty")
+	}
+
+	parser := &tagParser{tokens: tokens}
+	root, err := parser.or()
+	if err != nil {
+		return TagExpression{}, err
+	}
+
+	if parser.next < len(tokens) {
+		return TagExpression{}, fmt.Errorf("unexpected %q in tag expression", tokens[parser.next])
+	}
+
+	return TagExpression{source: strings.TrimSpace(source), root: root}, nil
+}
+
+func (expression TagExpression) Matches(friend Friend) bool {
+	if expression.root == nil {
+		return true
+	}
+
+	tags := map[string]bool{}
+	for _, tag := range friend.Tags {
+		tags[normalizeTag(tag)] = true
+	}
+
+	return expression.root.matches(tags)
+}
+
+func (expression TagExpression) String() string {
+	return expression.source
+}
+
+// FilterFriends returns the friends matching expression.
+func FilterFriends(friends []Friend, expression TagExpression) []Friend {
+	matches := []Friend{}
+	for _, friend := range friends {
+		if expression.Matches(friend) {
+			matches = append(matches, friend)
+		}
+	}
+
+	return matches
+}
+
+func tokenizeTags(source string) []string {
+	tokens := []string{}
+	current := strings.Builder{}
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range source {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func (parser *tagParser) or() (tagNode, error) {
+	left, err := parser.and()
+	for err == nil && parser.accept("OR") {
+		var right tagNode
+		if right, err = parser.and(); err == nil {
+			left = tagOr{left, right}
+		}
+	}
+
+	return left, err
+}
+
+func (parser *tagParser) and() (tagNode, error) {
+	left, err := parser.not()
+	for err == nil && parser.accept("AND") {
+		var right tagNode
+		if right, err = parser.not(); err == nil {
+			left = tagAnd{left, right}
+		}
+	}
+
+	return left, err
+}
+
+func (parser *tagParser) not() (tagNode, error) {
+	if parser.accept("NOT") {
+		operand, err := parser.not()
+		return tagNot{operand}, err
+	}
+
+	return parser.primary()
+}
+
+func (parser *tagParser) primary() (tagNode, error) {
+	if parser.next >= len(parser.tokens) {
+		return nil, errors.New("unexpected end of tag expression")
+	}
+
+	token := parser.tokens[parser.next]
+	parser.next++
+
+	switch {
+	case token == "(":
+		node, err := parser.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if !parser.accept(")") {
+			return nil, errors.New("missing closing parenthesis in tag expression")
+		}
+
+		return node, nil
+	case token == ")" || isTagKeyword(token):
+		return nil, fmt.Errorf("unexpected %q in tag expression", token)
+	}
+
+	return tagLeaf(normalizeTag(token)), nil
+}
+
+func (parser *tagParser) accept(token string) bool {
+	if parser.next < len(parser.tokens) && strings.EqualFold(parser.tokens[parser.next], token) {
+		parser.next++
+		return true
+	}
+
+	return false
+}
+
+func isTagKeyword(token string) bool {
+	switch strings.ToUpper(token) {
+	case "AND", "OR", "NOT":
+		return true
+	}
+
+	return false
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// TaggedFriendsRepository only returns the friends of repo matching its tag
+// expression, so any send, digest or report built on it is limited to them.
+type TaggedFriendsRepository struct {
+	repo       FriendsRepository
+	expression TagExpression
+}
+
+func NewTaggedFriendsRepository(repo FriendsRepository, expression TagExpression) TaggedFriendsRepository {
+	return TaggedFriendsRepository{repo: repo, expression: expression}
+}
+
+func (repo TaggedFriendsRepository) GetFriends() ([]Friend, error) {
+	friends, err := repo.repo.GetFriends()
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterFriends(friends, repo.expression), nil
+}
@@ -0,0 +1,116 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func taggedFriends() []Friend {
+	return []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com", Tags: []string{"work", "contractors"}},
+		{FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/08", Email: "mary.ann@foobar.com", Tags: []string{"Work"}},
+		{FirstName: "Jane", LastName: "Smith", BirthDate: "1990/05/15", Email: "jane.smith@example.com", Tags: []string{"family"}},
+	}
+}
+
+func firstNames(friends []Friend) []string {
+	names := []string{}
+	for _, friend := range friends {
+		names = append(names, friend.FirstName)
+	}
+
+	return names
+}
+
+func TestTagExpressionsMatchFriends(t *testing.T) {
+	cases := map[string][]string{
+		"work":                             {"John", "Mary"},
+		"work AND NOT contractors":         {"Mary"},
+		"family or contractors":            {"John", "Jane"},
+		"NOT work":                         {"Jane"},
+		"family OR work AND contractors":   {"John", "Jane"},
+		"(family OR work) and contractors": {"John"},
+		"NOT (work OR family)":             {},
+	}
+
+	for source, want := range cases {
+		expression, err := ParseTagExpression(source)
+		if err != nil {
+			t.Errorf("Expected %q to parse but got '%v'", source, err)
+			continue
+		}
+
+		if got := firstNames(FilterFriends(taggedFriends(), expression)); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %q to match %v but got %v", source, want, got)
+		}
+	}
+}
+
+func TestParseTagExpressionRejectsInvalidExpressions(t *testing.T) {
+	for _, source := range []string{"", "work AND", "(work", "work)", "AND work", "work family", "NOT"} {
+		if _, err := ParseTagExpression(source); err == nil {
+			t.Errorf("Expected %q to be rejected but was not", source)
+		}
+	}
+}
+
+func TestZeroTagExpressionMatchesEveryFriend(t *testing.T) {
+	if got := FilterFriends(taggedFriends(), TagExpression{}); len(got) != 3 {
+		t.Errorf("Expected every friend but got %v", got)
+	}
+}
+
+func TestTaggedFriendsRepositoryFiltersFriends(t *testing.T) {
+	expression, _ := ParseTagExpression("work AND NOT contractors")
+	repository := NewTaggedFriendsRepository(stubFriendsRepository{friends: taggedFriends()}, expression)
+
+	friends, err := repository.GetFriends()
+	if err != nil || !reflect.DeepEqual(firstNames(friends), []string{"Mary"}) {
+		t.Errorf("Expected only Mary but got %v, '%v'", friends, err)
+	}
+}
+
+func TestFriendsFileTagsColumnRoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friends.txt")
+	content := "last_name,first_name,birth_date,email,tags\nDoe,John,1982/10/08,john.doe@foobar.com, Work ; Contractors\nAnn,Mary,1975/09/11,mary.ann@foobar.com,\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	repository := TextFileFriendsRepository{path: path}
+
+	friends, err := repository.GetFriends()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(friends[0].Tags, []string{"work", "contractors"}) || friends[1].Tags != nil {
+		t.Errorf("Expected John's tags to be read but got %v and %v", friends[0].Tags, friends[1].Tags)
+	}
+
+	if err := repository.SaveFriends(friends); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	saved, _ := os.ReadFile(path)
+	want := "last_name,first_name,birth_date,email,tags\nDoe,John,1982/10/08,john.doe@foobar.com,work;contractors\nAnn,Mary,1975/09/11,mary.ann@foobar.com,\n"
+	if string(saved) != want {
+		t.Errorf("Expected %q but got %q", want, string(saved))
+	}
+}
+
+func TestBuildBirthdayDigestFiltersByTags(t *testing.T) {
+	expression, _ := ParseTagExpression("work AND NOT contractors")
+	config := DigestConfig{Recipients: []string{"organizer@example.com"}, Tags: expression}
+
+	digest, err := BuildBirthdayDigest(taggedFriends(), time.Date(2024, 10, 8, 0, 0, 0, 0, time.UTC), config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(firstNames(digest.birthdays), []string{"Mary"}) {
+		t.Errorf("Expected only Mary in the digest but got %v", digest.birthdays)
+	}
+}
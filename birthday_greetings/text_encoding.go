@@ -0,0 +1,108 @@
+package birthday_greetings
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/unicode/norm"
+)
+
+type TextEncoding string
+
+const (
+	UTF8        TextEncoding = "utf-8"
+	Latin1      TextEncoding = "latin-1"
+	Windows1252 TextEncoding = "windows-1252"
+)
+
+// TextFileOptions describes the dialect of a friends file. The zero value is
+// the birthdays.txt format: UTF-8, comma separated, without comments.
+type TextFileOptions struct {
+	Encoding  TextEncoding
+	Delimiter rune
+	Comment   rune
+}
+
+var byteOrderMarks = []struct {
+	mark     []byte
+	encoding encoding.Encoding
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, nil},
+	{[]byte{0xFF, 0xFE}, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+	{[]byte{0xFE, 0xFF}, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+}
+
+func (options TextFileOptions) delimiter() rune {
+	if options.Delimiter == 0 {
+		return ','
+	}
+
+	return options.Delimiter
+}
+
+func (options TextFileOptions) encoding() (encoding.Encoding, error) {
+	switch TextEncoding(strings.ToLower(string(options.Encoding))) {
+	case "", UTF8, "utf8":
+		return nil, nil
+	case Latin1, "iso-8859-1":
+		return charmap.ISO8859_1, nil
+	case Windows1252, "cp1252":
+		return charmap.Windows1252, nil
+	}
+
+	return nil, fmt.Errorf("unsupported text encoding %q", options.Encoding)
+}
+
+// decodeText strips a byte order mark and converts data to UTF-8. A UTF-16
+// byte order mark wins over the configured encoding, while a UTF-8 one is
+// only stripped, as legacy tools add it to files in other encodings too.
+func (options TextFileOptions) decodeText(data []byte) ([]byte, error) {
+	source, err := options.encoding()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bom := range byteOrderMarks {
+		if stripped, found := bytes.CutPrefix(data, bom.mark); found {
+			data = stripped
+			if bom.encoding != nil {
+				source = bom.encoding
+			}
+			break
+		}
+	}
+
+	if source == nil {
+		return data, nil
+	}
+
+	return source.NewDecoder().Bytes(data)
+}
+
+func (options TextFileOptions) encodeText(data []byte) ([]byte, error) {
+	target, err := options.encoding()
+	if err != nil || target == nil {
+		return data, err
+	}
+
+	encoded, err := target.NewEncoder().Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("friends cannot be written as %s: %w", options.Encoding, err)
+	}
+
+	return encoded, nil
+}
+
+// normalizeFields converts every field to Unicode NFC, so that names typed
+// with combining accents match their precomposed spelling.
+func normalizeFields(rows [][]string) {
+	for _, row := range rows {
+		for i, field := range row {
+			row[i] = norm.NFC.String(field)
+		}
+	}
+}
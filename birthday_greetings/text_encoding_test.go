@@ -0,0 +1,106 @@
+package birthday_greetings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeEncodedFriendsFile(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "birthdays.txt")
+	os.WriteFile(path, data, 0o600)
+	return path
+}
+
+func TestGetFriendsStripsUTF8ByteOrderMark(t *testing.T) {
+	path := writeEncodedFriendsFile(t, []byte("\xEF\xBB\xBFlast_name, first_name, birth_date, email\nDoe, Zoë, 1982/10/08, zoe.doe@foobar.com\n"))
+
+	friends, err := NewTextFileFriendsRepository(path, TextFileOptions{}).GetFriends()
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if len(friends) != 1 || friends[0].FirstName != "Zoë" || friends[0].LastName != "Doe" {
+		t.Errorf("Expected the header to be detected after the BOM but got %v", friends)
+	}
+}
+
+func TestGetFriendsDecodesConfiguredEncoding(t *testing.T) {
+	tests := []struct {
+		encoding TextEncoding
+		data     []byte
+		want     string
+	}{
+		{Latin1, []byte("M\xFCller, Zo\xEB, 1982/10/08, zoe.muller@foobar.com\n"), "Müller Zoë"},
+		{Windows1252, []byte("\xEF\xBB\xBFM\xFCller, Fran\xE7ois\x99, 1982/10/08, francois@foobar.com\n"), "Müller François™"},
+	}
+
+	for _, test := range tests {
+		friends, err := NewTextFileFriendsRepository(writeEncodedFriendsFile(t, test.data), TextFileOptions{Encoding: test.encoding}).GetFriends()
+		if err != nil {
+			t.Fatalf("Expected no error but got '%v'", err.Error())
+		}
+
+		if got := friends[0].LastName + " " + friends[0].FirstName; got != test.want {
+			t.Errorf("Expected %q but got %q", test.want, got)
+		}
+	}
+}
+
+func TestGetFriendsDecodesUTF16WithByteOrderMark(t *testing.T) {
+	data := []byte{0xFF, 0xFE}
+	for _, r := range "Doe, Zoë, 1982/10/08, zoe.doe@foobar.com\n" {
+		data = append(data, byte(r), byte(r>>8))
+	}
+
+	friends, err := NewTextFileFriendsRepository(writeEncodedFriendsFile(t, data), TextFileOptions{Encoding: Latin1}).GetFriends()
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if friends[0].FirstName != "Zoë" {
+		t.Errorf("Expected Zoë but got %q", friends[0].FirstName)
+	}
+}
+
+func TestGetFriendsNormalizesNamesToNFC(t *testing.T) {
+	decomposed := writeEncodedFriendsFile(t, []byte("Doe, Zoe\u0308, 1982/10/08, zoe.doe@foobar.com\n"))
+	composed := writeEncodedFriendsFile(t, []byte("Doe, Zo\u00EB, 1982/10/08, zoe.doe@foobar.com\n"))
+
+	fromDecomposed, _ := NewTextFileFriendsRepository(decomposed, TextFileOptions{}).GetFriends()
+	fromComposed, _ := NewTextFileFriendsRepository(composed, TextFileOptions{}).GetFriends()
+
+	if fromDecomposed[0].FirstName != "Zo\u00EB" || fromDecomposed[0].ID != fromComposed[0].ID {
+		t.Errorf("Expected both spellings to read as the same friend but got %v and %v", fromDecomposed[0], fromComposed[0])
+	}
+}
+
+func TestTextFileDialectRoundTrip(t *testing.T) {
+	path := writeEncodedFriendsFile(t, []byte("# exported from the legacy tool\nM\xFCller; Zo\xEB; 1982/10/08; zoe.muller@foobar.com\n"))
+	repo := NewTextFileFriendsRepository(path, TextFileOptions{Encoding: Latin1, Delimiter: ';', Comment: '#'})
+
+	friends, err := repo.GetFriends()
+	if err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	if err := repo.SaveFriends(friends); err != nil {
+		t.Fatalf("Expected no error but got '%v'", err.Error())
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "# exported from the legacy tool\nM\xFCller; Zo\xEB; 1982/10/08; zoe.muller@foobar.com\n" {
+		t.Errorf("Expected the friends in Latin-1 with semicolons but got %q", data)
+	}
+
+	friends[0].FirstName = "Zoë 🎂"
+	if err := repo.SaveFriends(friends); err == nil {
+		t.Errorf("Expected characters outside Latin-1 to be rejected")
+	}
+}
+
+func TestUnsupportedTextEncoding(t *testing.T) {
+	if _, err := NewTextFileFriendsRepository("birthdays.txt", TextFileOptions{Encoding: "ebcdic"}).GetFriends(); err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
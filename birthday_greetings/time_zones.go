@@ -0,0 +1,37 @@
+package birthday_greetings
+
+import "time"
+
+// location returns the friend's IANA time zone, or nil when none is set, in
+// which case times are kept in the location they were given in.
+func (friend Friend) location() (*time.Location, error) {
+	if friend.TimeZone == "" {
+		return nil, nil
+	}
+
+	return time.LoadLocation(friend.TimeZone)
+}
+
+func (friend Friend) localTime(instant time.Time) (time.Time, error) {
+	location, err := friend.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if location == nil {
+		return instant, nil
+	}
+
+	return instant.In(location), nil
+}
+
+// IsBirthdayAt reports whether instant falls on the friend's birthday in the
+// friend's own time zone.
+func (friend Friend) IsBirthdayAt(instant time.Time) (bool, error) {
+	local, err := friend.localTime(instant)
+	if err != nil {
+		return false, err
+	}
+
+	return friend.IsBirthdayOn(local)
+}
@@ -0,0 +1,34 @@
+package birthday_greetings
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsBirthdayAtUsesFriendLocalDate(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com", TimeZone: "Asia/Tokyo"}
+	instant := time.Date(2024, 10, 7, 20, 0, 0, 0, time.UTC)
+
+	isBirthday, err := friend.IsBirthdayAt(instant)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if !isBirthday {
+		t.Errorf("Expected it to already be October 8 in Tokyo")
+	}
+}
+
+func TestIsBirthdayAtWithoutTimeZoneUsesGivenLocation(t *testing.T) {
+	friend := Friend{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	instant := time.Date(2024, 10, 7, 20, 0, 0, 0, time.UTC)
+
+	isBirthday, err := friend.IsBirthdayAt(instant)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if isBirthday {
+		t.Errorf("Expected it to still be October 7")
+	}
+}
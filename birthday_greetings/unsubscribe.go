@@ -0,0 +1,126 @@
+package birthday_greetings
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// UnsubscribeSigner issues and verifies HMAC-SHA256 signed unsubscribe
+// tokens, so that only links from our own emails can opt someone out.
+type UnsubscribeSigner struct {
+	key     []byte
+	baseURL string
+}
+
+func NewUnsubscribeSigner(key []byte, baseURL string) (UnsubscribeSigner, error) {
+	if len(key) < 32 {
+		return UnsubscribeSigner{}, errors.New("unsubscribe key must be at least 32 bytes")
+	}
+
+	if _, err := url.Parse(baseURL); err != nil || baseURL == "" {
+		return UnsubscribeSigner{}, errors.New("invalid unsubscribe base url")
+	}
+
+	return UnsubscribeSigner{key: key, baseURL: baseURL}, nil
+}
+
+func (signer UnsubscribeSigner) Token(friendID string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(friendID))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signer.sign(payload))
+}
+
+// Verify returns the friend ID a valid token was issued for.
+func (signer UnsubscribeSigner) Verify(token string) (string, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", errors.New("invalid unsubscribe token")
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, signer.sign(payload)) {
+		return "", errors.New("invalid unsubscribe token")
+	}
+
+	friendID, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", errors.New("invalid unsubscribe token")
+	}
+
+	return string(friendID), nil
+}
+
+func (signer UnsubscribeSigner) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, signer.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Headers returns the List-Unsubscribe headers for a friend, advertising
+// one-click unsubscription as described in RFC 8058.
+func (signer UnsubscribeSigner) Headers(friendID string) map[string]string {
+	link, _ := url.Parse(signer.baseURL)
+	query := link.Query()
+	query.Set("token", signer.Token(friendID))
+	link.RawQuery = query.Encode()
+
+	return map[string]string{
+		"List-Unsubscribe":      "<" + link.String() + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+type UnsubscribeHandler struct {
+	signer UnsubscribeSigner
+	store  OptOutStore
+}
+
+func NewUnsubscribeHandler(signer UnsubscribeSigner, store OptOutStore) UnsubscribeHandler {
+	return UnsubscribeHandler{signer: signer, store: store}
+}
+
+// unsubscribeConfirmation posts back to the same URL, token included, with
+// the body of an RFC 8058 one-click request.
+const unsubscribeConfirmation = `<!DOCTYPE html>
+<title>Unsubscribe</title>
+<form method="post">
+<input type="hidden" name="List-Unsubscribe" value="One-Click">
+<p>Stop receiving greetings?</p>
+<button type="submit">Unsubscribe</button>
+</form>
+`
+
+// ServeHTTP opts the friend out on POST, as sent by one-click capable mail
+// clients. GET only shows a confirmation form, since link scanners and
+// prefetchers follow links without anyone clicking them.
+func (handler UnsubscribeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	friendID, err := handler.signer.Verify(r.URL.Query().Get("token"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, unsubscribeConfirmation)
+		return
+	}
+
+	if err := handler.store.OptOut(friendID); err != nil {
+		http.Error(w, "could not record opt-out", http.StatusInternalServerError)
+		return
+	}
+
+	fmt.Fprintln(w, "You have been unsubscribed from greetings.")
+}
@@ -0,0 +1,167 @@
+package birthday_greetings
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testUnsubscribeSigner(t *testing.T) UnsubscribeSigner {
+	t.Helper()
+	signer, err := NewUnsubscribeSigner([]byte("0123456789abcdef0123456789abcdef"), "https://greetings.example.com/unsubscribe")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	return signer
+}
+
+func TestUnsubscribeTokenRoundTrip(t *testing.T) {
+	signer := testUnsubscribeSigner(t)
+
+	friendID, err := signer.Verify(signer.Token("c4f77c3c468f1411"))
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if friendID != "c4f77c3c468f1411" {
+		t.Errorf("Expected 'c4f77c3c468f1411' but got '%s'", friendID)
+	}
+}
+
+func TestUnsubscribeTokenWithTamperedPayload(t *testing.T) {
+	signer := testUnsubscribeSigner(t)
+	_, signature, _ := strings.Cut(signer.Token("c4f77c3c468f1411"), ".")
+	forged := strings.Split(signer.Token("1ce90aa149d9fed9"), ".")[0] + "." + signature
+
+	_, err := signer.Verify(forged)
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestNewUnsubscribeSignerWithShortKey(t *testing.T) {
+	_, err := NewUnsubscribeSigner([]byte("short"), "https://greetings.example.com/unsubscribe")
+
+	if err == nil {
+		t.Errorf("Expected error to be raised but was not")
+	}
+}
+
+func TestUnsubscribeHandlerRecordsOptOut(t *testing.T) {
+	signer := testUnsubscribeSigner(t)
+	store := NewMemoryOptOutStore()
+	handler := NewUnsubscribeHandler(signer, store)
+	request := httptest.NewRequest(http.MethodPost, "/unsubscribe?token="+url.QueryEscape(signer.Token("c4f77c3c468f1411")), nil)
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200 but got %d", recorder.Code)
+	}
+
+	if optedOut, _ := store.IsOptedOut("c4f77c3c468f1411"); !optedOut {
+		t.Errorf("Expected John to be opted out")
+	}
+}
+
+func TestUnsubscribeHandlerWithInvalidToken(t *testing.T) {
+	handler := NewUnsubscribeHandler(testUnsubscribeSigner(t), NewMemoryOptOutStore())
+	request := httptest.NewRequest(http.MethodGet, "/unsubscribe?token=forged", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 but got %d", recorder.Code)
+	}
+}
+
+func TestSchedulerSkipsOptedOutFriendsAndAddsUnsubscribeHeaders(t *testing.T) {
+	repository := stubFriendsRepository{friends: []Friend{
+		{FirstName: "John", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+		{ID: "mary", FirstName: "Mary", LastName: "Ann", BirthDate: "1975/10/08", Email: "mary.ann@foobar.com"},
+	}}
+	store := NewMemoryOptOutStore()
+	store.OptOut("mary")
+	signer := testUnsubscribeSigner(t)
+	scheduler := NewScheduler(repository, SchedulerConfig{
+		Window:      SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour},
+		OptOuts:     store,
+		Unsubscribe: &signer,
+	})
+
+	if err := scheduler.Run(time.Date(2024, 10, 8, 6, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	held, _ := scheduler.heldGreetings()
+	if len(held) != 1 || held[0].Recipient != "john.doe@foobar.com" {
+		t.Fatalf("Expected only John's greeting to be scheduled")
+	}
+
+	if !strings.Contains(held[0].Headers["List-Unsubscribe"], "token=") {
+		t.Errorf("Expected a List-Unsubscribe header with a token but got %v", held[0].Headers)
+	}
+}
+
+func TestUnsubscribeHandlerOnlyConfirmsOnGet(t *testing.T) {
+	signer := testUnsubscribeSigner(t)
+	store := NewMemoryOptOutStore()
+	handler := NewUnsubscribeHandler(signer, store)
+	request := httptest.NewRequest(http.MethodGet, "/unsubscribe?token="+url.QueryEscape(signer.Token("c4f77c3c468f1411")), nil)
+	recorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `<form method="post">`) {
+		t.Errorf("Expected a confirmation form but got %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	if optedOut, _ := store.IsOptedOut("c4f77c3c468f1411"); optedOut {
+		t.Errorf("Expected John not to be opted out by a GET")
+	}
+}
+
+func TestUnsubscribeHeadersKeepBaseURLQuery(t *testing.T) {
+	signer, _ := NewUnsubscribeSigner([]byte("0123456789abcdef0123456789abcdef"), "https://greetings.example.com/unsubscribe?list=birthdays")
+
+	header := signer.Headers("c4f77c3c468f1411")["List-Unsubscribe"]
+	link, err := url.Parse(strings.Trim(header, "<>"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if link.Query().Get("list") != "birthdays" || link.Query().Get("token") != signer.Token("c4f77c3c468f1411") {
+		t.Errorf("Expected the list and token parameters but got %s", header)
+	}
+}
+
+func TestSchedulerMigratesOptOutsRecordedByEmail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opt_outs.txt")
+	os.WriteFile(path, []byte("john.doe@foobar.com\n"), 0o600)
+	store := NewFileOptOutStore(path)
+	scheduler := NewScheduler(stubFriendsRepository{friends: []Friend{
+		{FirstName: "Johnny", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"},
+	}}, SchedulerConfig{Window: SendWindow{Start: 8 * time.Hour, End: 20 * time.Hour}, OptOuts: store})
+
+	if err := scheduler.Run(time.Date(2024, 10, 8, 6, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if scheduler.Held() != 0 {
+		t.Errorf("Expected the opt-out recorded by email to be honoured")
+	}
+
+	friend := Friend{FirstName: "Johnny", LastName: "Doe", BirthDate: "1982/10/08", Email: "john.doe@foobar.com"}
+	if optedOut, _ := store.IsOptedOut(friend.identity()); !optedOut {
+		t.Errorf("Expected the opt-out to be recorded under the friend ID")
+	}
+}
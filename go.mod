@@ -1,3 +1,8 @@
 module github.com/XxSachaxX/go-katas
 
 go 1.25
+
+require (
+	golang.org/x/text v0.29.0
+	gopkg.in/yaml.v3 v3.0.1
+)